@@ -0,0 +1,28 @@
+package http
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+)
+
+// BackoffExponentialJitter returns backoff func with exponential delay starting from base
+// and capped at max, full jitter applied to delay, so retries from many clients are spread,
+// first attempt is not delayed
+func BackoffExponentialJitter(base, max time.Duration) client.BackoffFunc {
+	return func(_ context.Context, _ client.Request, attempts int) (time.Duration, error) {
+		if attempts <= 0 || base <= 0 || max <= 0 {
+			return 0, nil
+		}
+		d := max
+		// avoid overflow on large attempts
+		if attempts < 63 {
+			if e := base << uint(attempts-1); e > 0 && e < max {
+				d = e
+			}
+		}
+		return time.Duration(rand.Int63n(int64(d) + 1)), nil // nolint: gosec
+	}
+}
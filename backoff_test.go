@@ -0,0 +1,29 @@
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffExponentialJitter(t *testing.T) {
+	base := 10 * time.Millisecond
+	max := 100 * time.Millisecond
+	fn := BackoffExponentialJitter(base, max)
+
+	if d, err := fn(context.TODO(), nil, 0); err != nil || d != 0 {
+		t.Fatalf("first attempt must not be delayed, got %v %v", d, err)
+	}
+
+	for attempt, limit := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 5: max, 100: max} {
+		for i := 0; i < 100; i++ {
+			d, err := fn(context.TODO(), nil, attempt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d < 0 || d > limit {
+				t.Fatalf("attempt %d delay %v out of [0, %v]", attempt, d, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/errors"
+)
+
+// BroadcastResult holds responses and errors of broadcast call by node address
+type BroadcastResult struct {
+	// Responses successful responses with the same type as passed to Broadcast
+	Responses map[string]interface{}
+	// Errors failed calls
+	Errors map[string]error
+}
+
+// Broadcast sends request to all nodes returned by lookup of client c concurrently, c must be
+// http client possibly wrapped by client wrappers, as broadcast passed through its Call,
+// proxy and WithURL options applied like in Call, rsp used as prototype for responses and not modified,
+// error returned if call failed before sending like lookup error or quorum passed via
+// WithBroadcastQuorum not reached
+func Broadcast(ctx context.Context, c client.Client, req client.Request, rsp interface{}, opts ...client.CallOption) (*BroadcastResult, error) {
+	if newResponse(rsp) == nil {
+		return nil, errors.BadRequest("go.micro.client", "broadcast response must be a pointer")
+	}
+
+	res := &BroadcastResult{}
+	nopts := make([]client.CallOption, 0, len(opts)+1)
+	nopts = append(nopts, opts...)
+	nopts = append(nopts, client.SetCallOption(broadcastKey{}, res))
+	err := c.Call(ctx, req, rsp, nopts...)
+	if res.Responses == nil {
+		if err == nil {
+			err = errors.InternalServerError("go.micro.client", "client does not support broadcast")
+		}
+		return nil, err
+	}
+
+	return res, err
+}
+
+// broadcast sends request to all nodes returned by lookup concurrently and stores results in res
+func (h *httpClient) broadcast(ctx context.Context, hcall client.CallFunc, req client.Request, rsp interface{}, callOpts client.CallOptions, res *BroadcastResult) error {
+	routes, err := h.opts.Lookup(ctx, req, callOpts)
+	if err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	// reset by client wrapper retrying Call
+	res.Responses = make(map[string]interface{}, len(routes))
+	res.Errors = make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, node := range routes {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			nrsp := newResponse(rsp)
+			start := time.Now()
+			err := hcall(copyOutgoingMetadata(ctx), node, req, nrsp, callOpts)
+			_ = h.record(node, time.Since(start), err)
+			mu.Lock()
+			if err != nil {
+				res.Errors[node] = err
+			} else {
+				res.Responses[node] = nrsp
+			}
+			mu.Unlock()
+		}(node)
+	}
+	wg.Wait()
+
+	if quorum, ok := callOpts.Context.Value(broadcastQuorumKey{}).(int); ok && len(res.Responses) < quorum {
+		return errors.New("go.micro.client", fmt.Sprintf("broadcast quorum not reached: %d of %d", len(res.Responses), quorum), 503)
+	}
+
+	return nil
+}
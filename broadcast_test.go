@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+func TestBroadcast(t *testing.T) {
+	handler := func(name string, status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"name":"` + name + `"}`))
+		})
+	}
+	ok1 := httptest.NewServer(handler("ok1", http.StatusOK))
+	defer ok1.Close()
+	ok2 := httptest.NewServer(handler("ok2", http.StatusOK))
+	defer ok2.Close()
+	fail := httptest.NewServer(handler("fail", http.StatusInternalServerError))
+	defer fail.Close()
+
+	// broadcast passed through client wrapper
+	c := wrappedClient{NewClient(client.Codec("application/json", codec.NewCodec()))}
+
+	req := c.NewRequest("test", "/test", &Request{})
+	addrs := client.WithAddress(ok1.URL, fail.URL, ok2.URL)
+
+	res, err := Broadcast(context.TODO(), c, req, &Request{}, addrs, WithBroadcastQuorum(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Responses) != 2 || len(res.Errors) != 1 {
+		t.Fatalf("invalid result %#+v", res)
+	}
+	for node, name := range map[string]string{ok1.URL: "ok1", ok2.URL: "ok2"} {
+		if rsp, ok := res.Responses[node].(*Request); !ok || rsp.Name != name {
+			t.Fatalf("invalid response of %s: %#+v", node, res.Responses[node])
+		}
+	}
+	if verr, ok := res.Errors[fail.URL].(*errors.Error); !ok || verr.Code != http.StatusInternalServerError {
+		t.Fatalf("invalid error of %s: %v", fail.URL, res.Errors[fail.URL])
+	}
+
+	res, err = Broadcast(context.TODO(), c, req, &Request{}, addrs, WithBroadcastQuorum(3))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusServiceUnavailable || res == nil || len(res.Responses) != 2 {
+		t.Fatalf("expected quorum error with result, got %v %#+v", err, res)
+	}
+
+	// absolute url replaces routed nodes like in Call
+	res, err = Broadcast(context.TODO(), c, req, &Request{}, addrs, WithURL(ok2.URL+"/test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp, ok := res.Responses[ok2.URL].(*Request); len(res.Responses) != 1 || len(res.Errors) != 0 || !ok || rsp.Name != "ok2" {
+		t.Fatalf("invalid url result %#+v", res)
+	}
+
+	// proxy address replaces routed nodes like in Call
+	pc := NewClient(client.Codec("application/json", codec.NewCodec()), client.Proxy(ok1.URL))
+	res, err = Broadcast(context.TODO(), pc, req, &Request{}, addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Responses) != 1 || res.Responses[ok1.URL] == nil {
+		t.Fatalf("invalid proxy result %#+v", res)
+	}
+}
+
+type wrappedClient struct {
+	client.Client
+}
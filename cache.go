@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache stores response bodies for cacheable requests
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, body []byte, ttl time.Duration)
+}
+
+// cacheTransport serves GET and HEAD requests from cache and stores cacheable responses,
+// whole response stored, so status and headers restored on hit, responses to requests
+// with credentials stored only if marked public
+type cacheTransport struct {
+	rt    http.RoundTripper
+	cache Cache
+}
+
+func (t *cacheTransport) RoundTrip(hreq *http.Request) (*http.Response, error) {
+	if hreq.Method != http.MethodGet && hreq.Method != http.MethodHead {
+		return t.rt.RoundTrip(hreq)
+	}
+
+	reqcc := parseCacheControl(hreq.Header)
+	if _, ok := reqcc["no-store"]; ok {
+		return t.rt.RoundTrip(hreq)
+	}
+
+	base := hreq.Method + " " + hreq.URL.String()
+	if _, ok := reqcc["no-cache"]; !ok {
+		if vary, ok := t.cache.Get("vary " + base); ok {
+			if buf, ok := t.cache.Get(cacheKey(base, hreq.Header, strings.Split(string(vary), "\n"))); ok {
+				if hrsp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf)), hreq); err == nil {
+					return hrsp, nil
+				}
+			}
+		}
+	}
+
+	hrsp, err := t.rt.RoundTrip(hreq)
+	if err != nil || hrsp.StatusCode != http.StatusOK {
+		return hrsp, err
+	}
+
+	ttl := cacheTTL(hrsp)
+	vary := hrsp.Header.Values("Vary")
+	if ttl <= 0 || strings.Contains(strings.Join(vary, ","), "*") {
+		return hrsp, nil
+	}
+	// response to credentialed request may be user specific, it shared only if marked public
+	if hreq.Header.Get("Authorization") != "" || hreq.Header.Get("Cookie") != "" {
+		if _, ok := parseCacheControl(hrsp.Header)["public"]; !ok {
+			return hrsp, nil
+		}
+	}
+
+	buf, err := httputil.DumpResponse(hrsp, true)
+	if err != nil {
+		_ = hrsp.Body.Close()
+		return nil, err
+	}
+
+	var names []string
+	for _, v := range vary {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	t.cache.Set("vary "+base, []byte(strings.Join(names, "\n")), ttl)
+	t.cache.Set(cacheKey(base, hreq.Header, names), buf, ttl)
+
+	return hrsp, nil
+}
+
+// cacheKey builds key from method, url and request headers listed in Vary
+func cacheKey(base string, header http.Header, vary []string) string {
+	var b strings.Builder
+	b.WriteString(base)
+	for _, name := range vary {
+		if name == "" {
+			continue
+		}
+		b.WriteString("\n")
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteString(":")
+		b.WriteString(strings.Join(header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// parseCacheControl returns Cache-Control directives with optional values
+func parseCacheControl(header http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+			if idx := strings.IndexByte(d, '='); idx > 0 {
+				cc[strings.ToLower(d[:idx])] = strings.Trim(d[idx+1:], `"`)
+			} else {
+				cc[strings.ToLower(d)] = ""
+			}
+		}
+	}
+	return cc
+}
+
+// cacheTTL returns response freshness lifetime from Cache-Control or Expires,
+// zero means response not cacheable
+func cacheTTL(hrsp *http.Response) time.Duration {
+	cc := parseCacheControl(hrsp.Header)
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[d]; ok {
+			return 0
+		}
+	}
+	if v, ok := cc["max-age"]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return 0
+		}
+		return time.Duration(n) * time.Second
+	}
+	if v := hrsp.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		now := time.Now()
+		if date, err := http.ParseTime(hrsp.Header.Get("Date")); err == nil {
+			now = date
+		}
+		return expires.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/metadata"
+)
+
+type memoryCache struct {
+	items map[string][]byte
+	sync.Mutex
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+	v, ok := c.items[key]
+	return v, ok
+}
+
+func (c *memoryCache) Set(key string, body []byte, ttl time.Duration) {
+	c.Lock()
+	c.items[key] = body
+	c.Unlock()
+}
+
+func TestResponseCache(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Header().Set("Vary", "X-Tenant")
+		_, _ = fmt.Fprintf(w, `{"name":"%s-%d"}`, r.Header.Get("X-Tenant"), n)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithResponseCache(&memoryCache{items: make(map[string][]byte)}))
+
+	call := func(path, method string, md metadata.Metadata) string {
+		rsp := &Request{}
+		ctx := metadata.NewOutgoingContext(context.TODO(), md)
+		if err := c.Call(ctx, c.NewRequest("test", path, &Request{}, WithMethod(method)), rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+		return rsp.Name
+	}
+
+	tests := []struct {
+		path   string
+		method string
+		md     metadata.Metadata
+		expect string
+	}{
+		{"/test", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-1"},
+		{"/test", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-1"},
+		{"/test", http.MethodGet, metadata.Metadata{"X-Tenant": "b"}, "b-2"},
+		{"/test", http.MethodGet, metadata.Metadata{"X-Tenant": "a", "Cache-Control": "no-cache"}, "a-3"},
+		{"/test", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-3"},
+		{"/test", http.MethodPost, metadata.Metadata{"X-Tenant": "a"}, "a-4"},
+		{"/nostore", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-5"},
+		{"/nostore", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-6"},
+		{"/private", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-7"},
+		{"/private", http.MethodGet, metadata.Metadata{"X-Tenant": "a"}, "a-8"},
+		// responses to different credentials never shared unless public
+		{"/auth", http.MethodGet, metadata.Metadata{"X-Tenant": "a", "Authorization": "Bearer u1"}, "a-9"},
+		{"/auth", http.MethodGet, metadata.Metadata{"X-Tenant": "a", "Authorization": "Bearer u2"}, "a-10"},
+		{"/public", http.MethodGet, metadata.Metadata{"X-Tenant": "a", "Authorization": "Bearer u1"}, "a-11"},
+		{"/public", http.MethodGet, metadata.Metadata{"X-Tenant": "a", "Authorization": "Bearer u2"}, "a-11"},
+	}
+
+	for i, tt := range tests {
+		if name := call(tt.path, tt.method, tt.md); name != tt.expect {
+			t.Fatalf("%d: expected %s, got %s", i, tt.expect, name)
+		}
+	}
+}
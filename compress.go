@@ -0,0 +1,134 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HeaderDecodedContentEncoding set in response metadata to original Content-Encoding
+// if response body decompressed, Content-Length removed in that case
+const HeaderDecodedContentEncoding = "X-Decoded-Content-Encoding"
+
+// ContentDecoder returns reader that decompress body with some Content-Encoding
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	contentDecodersMu sync.RWMutex
+	contentDecoders   = map[string]ContentDecoder{
+		"gzip":    gzipDecoder,
+		"x-gzip":  gzipDecoder,
+		"deflate": deflateDecoder,
+	}
+)
+
+// RegisterContentDecoder registers decoder for response Content-Encoding,
+// it can be used to add encodings not supported by default, for example br,
+// nil fn removes decoder
+func RegisterContentDecoder(encoding string, fn ContentDecoder) {
+	contentDecodersMu.Lock()
+	if fn == nil {
+		delete(contentDecoders, strings.ToLower(encoding))
+	} else {
+		contentDecoders[strings.ToLower(encoding)] = fn
+	}
+	contentDecodersMu.Unlock()
+}
+
+func gzipDecoder(r io.Reader) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	// all concatenated gzip members are read
+	zr.Multistream(true)
+	return zr, nil
+}
+
+// deflateDecoder handles zlib wrapped stream as specified by rfc
+// and raw deflate stream that sent by some servers
+func deflateDecoder(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	hdr, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decodedBody closes both decoder readers and underlying body
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// decompressBody decodes body that not decompressed by transport,
+// this happens when Accept-Encoding header passed by caller or server
+// uses encoding other than gzip, encodings are removed in reverse order
+func decompressBody(hrsp *http.Response) error {
+	if hrsp.Uncompressed && hrsp.Header.Get(HeaderDecodedContentEncoding) == "" {
+		// transport decompress only gzip
+		hrsp.Header.Set(HeaderDecodedContentEncoding, "gzip")
+	}
+
+	ce := hrsp.Header.Get("Content-Encoding")
+	if hrsp.Body == nil || hrsp.Body == http.NoBody || len(ce) == 0 {
+		return nil
+	}
+
+	encodings := strings.Split(ce, ",")
+	body := &decodedBody{Reader: hrsp.Body, closers: []io.Closer{hrsp.Body}}
+
+	contentDecodersMu.RLock()
+	defer contentDecodersMu.RUnlock()
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		enc := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if enc == "" || enc == "identity" {
+			continue
+		}
+		fn, ok := contentDecoders[enc]
+		if !ok {
+			return fmt.Errorf("unsupported response Content-Encoding %q", enc)
+		}
+		rd, err := fn(body.Reader)
+		if err == io.EOF {
+			// empty body
+			body.Reader = http.NoBody
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %s response body: %v", enc, err)
+		}
+		body.Reader = rd
+		body.closers = append(body.closers, rd)
+	}
+
+	hrsp.Body = body
+	hrsp.Header.Del("Content-Encoding")
+	hrsp.Header.Set(HeaderDecodedContentEncoding, ce)
+	// original length is not equal to decoded body length
+	hrsp.Header.Del("Content-Length")
+	hrsp.ContentLength = -1
+	hrsp.Uncompressed = true
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/metadata"
+)
+
+func TestGzipMultistream(t *testing.T) {
+	var body bytes.Buffer
+	for _, part := range []string{`{"name":`, `"vtolstov"}`} {
+		zw := gzip.NewWriter(&body)
+		_, _ = zw.Write([]byte(part))
+		_ = zw.Close()
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(body.Bytes())
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	// transport decompress body itself only if it set Accept-Encoding
+	for _, md := range []metadata.Metadata{nil, {"Accept-Encoding": "gzip"}} {
+		ctx := context.TODO()
+		if md != nil {
+			ctx = metadata.NewOutgoingContext(ctx, md)
+		}
+		rsp := &Request{}
+		if err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != "vtolstov" {
+			t.Fatalf("invalid response %#+v", rsp)
+		}
+	}
+}
+
+func TestDeflateResponse(t *testing.T) {
+	var zbody, fbody bytes.Buffer
+	zw := zlib.NewWriter(&zbody)
+	_, _ = zw.Write([]byte(`{"name":"vtolstov"}`))
+	_ = zw.Close()
+	fw, _ := flate.NewWriter(&fbody, flate.DefaultCompression)
+	_, _ = fw.Write([]byte(`{"name":"vtolstov"}`))
+	_ = fw.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	for _, body := range [][]byte{zbody.Bytes(), fbody.Bytes()} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "deflate")
+			_, _ = w.Write(body)
+		}))
+
+		rsp := &Request{}
+		err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL))
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != "vtolstov" {
+			t.Fatalf("invalid response %#+v", rsp)
+		}
+	}
+}
+
+func TestUnsupportedEncoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "x-test")
+		_, _ = w.Write([]byte(`tset-x{"name":"vtolstov"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL))
+	if err == nil || !strings.Contains(err.Error(), "x-test") {
+		t.Fatalf("expected unsupported encoding error, got %v", err)
+	}
+
+	RegisterContentDecoder("x-test", func(r io.Reader) (io.ReadCloser, error) {
+		buf := make([]byte, 6)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(r), nil
+	})
+	defer RegisterContentDecoder("x-test", nil)
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "vtolstov" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+func TestDecodedContentEncoding(t *testing.T) {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, _ = zw.Write([]byte(`{"name":"vtolstov"}`))
+	_ = zw.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(body.Bytes())
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	// decompressed by transport and by client
+	for _, md := range []metadata.Metadata{nil, {"Accept-Encoding": "gzip"}} {
+		ctx := context.TODO()
+		if md != nil {
+			ctx = metadata.NewOutgoingContext(ctx, md)
+		}
+		var rmd metadata.Metadata
+		if err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL), ResponseMetadata(&rmd)); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := rmd.Get(HeaderDecodedContentEncoding); v != "gzip" {
+			t.Fatalf("expected decoded encoding, got %v", rmd)
+		}
+		if _, ok := rmd.Get("Content-Length"); ok {
+			t.Fatalf("content length must be removed, got %v", rmd)
+		}
+	}
+}
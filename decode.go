@@ -0,0 +1,75 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+type elementHandler struct {
+	newElem func() interface{}
+	handler func(interface{}) error
+}
+
+// readElements decodes response body with newline delimited messages or array of messages,
+// each element passed to handler as soon as it decoded, so on error all elements before it
+// already delivered
+func readElements(body io.Reader, ct string, cf codec.Codec, eh elementHandler) error {
+	br := bufio.NewReader(body)
+	if !strings.HasPrefix(ct, "application/x-ndjson") && isArrayRoot(br) {
+		return readArrayElements(br, cf, eh)
+	}
+
+	var n int
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			elem := eh.newElem()
+			if uerr := cf.Unmarshal(line, elem); uerr != nil {
+				return errors.InternalServerError("go.micro.client", fmt.Sprintf("element %d: %v", n, uerr))
+			}
+			if herr := eh.handler(elem); herr != nil {
+				return herr
+			}
+			n++
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.InternalServerError("go.micro.client", fmt.Sprintf("element %d: %v", n, err))
+		}
+	}
+}
+
+func readArrayElements(r io.Reader, cf codec.Codec, eh elementHandler) error {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	for n := 0; dec.More(); n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return errors.InternalServerError("go.micro.client", fmt.Sprintf("element %d: %v", n, err))
+		}
+		elem := eh.newElem()
+		if err := cf.Unmarshal(raw, elem); err != nil {
+			return errors.InternalServerError("go.micro.client", fmt.Sprintf("element %d: %v", n, err))
+		}
+		if err := eh.handler(elem); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestElementHandlerPartial(t *testing.T) {
+	for ct, body := range map[string]string{
+		"application/json":     `[{"name":"first"},{"name":"second"},{"name":`,
+		"application/x-ndjson": "{\"name\":\"first\"}\n{\"name\":\"second\"}\n{\"name\":\n",
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", ct)
+			_, _ = w.Write([]byte(body))
+		}))
+
+		c := NewClient(
+			client.Codec("application/json", codec.NewCodec()),
+			client.Codec("application/x-ndjson", codec.NewCodec()),
+		)
+
+		var names []string
+		req := c.NewRequest("test", "/test", &Request{})
+		err := c.Call(context.TODO(), req, nil, client.WithAddress(ts.URL),
+			WithElementHandler(
+				func() interface{} { return &Request{} },
+				func(elem interface{}) error {
+					names = append(names, elem.(*Request).Name)
+					return nil
+				},
+			),
+		)
+		ts.Close()
+
+		if err == nil {
+			t.Fatalf("%s: malformed element must return error", ct)
+		}
+		if len(names) != 2 || names[0] != "first" || names[1] != "second" {
+			t.Fatalf("%s: decoded elements must be delivered, got %v", ct, names)
+		}
+	}
+}
@@ -2,4 +2,7 @@ module go.unistack.org/micro-client-http/v3
 
 go 1.16
 
-require go.unistack.org/micro/v3 v3.10.42
+require (
+	go.unistack.org/micro/v3 v3.10.42
+	golang.org/x/net v0.17.0
+)
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.unistack.org/micro/v3/errors"
+)
+
+const (
+	// grpcWebDataFrame flag of message frame
+	grpcWebDataFrame byte = 0x00
+	// grpcWebCompressedFrame flag bit of compressed message frame
+	grpcWebCompressedFrame byte = 0x01
+	// grpcWebTrailerFrame flag bit of frame with trailers
+	grpcWebTrailerFrame byte = 0x80
+)
+
+// grpcWebContentType returns grpc-web content type for codec content type
+func grpcWebContentType(ct string) string {
+	if strings.Contains(ct, "json") {
+		return "application/grpc-web+json"
+	}
+	return "application/grpc-web+proto"
+}
+
+// grpcWebFrame prepends message with 5 byte grpc length prefix
+func grpcWebFrame(b []byte) []byte {
+	buf := make([]byte, 5+len(b))
+	buf[0] = grpcWebDataFrame
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(b)))
+	copy(buf[5:], b)
+	return buf
+}
+
+// grpcWebDeframe replaces response body with first message and returns error for non zero grpc-status,
+// status read from trailer frame or from headers for trailers-only responses,
+// frames larger than maxSize rejected
+func grpcWebDeframe(service string, hrsp *http.Response, maxSize int) error {
+	var msg []byte
+	trailer := http.Header{}
+	br := bufio.NewReader(hrsp.Body)
+	hdr := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(br, hdr); err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.New("go.micro.client", fmt.Sprintf("incomplete grpc-web frame: %v", err), http.StatusBadGateway)
+		}
+		// length prefix checked before allocation, so broken or hostile peer can not exhaust memory
+		size := binary.BigEndian.Uint32(hdr[1:5])
+		if uint64(size) > uint64(maxSize) {
+			return errors.New("go.micro.client", fmt.Sprintf("grpc-web frame size %d exceeds limit %d", size, maxSize), http.StatusBadGateway)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return errors.New("go.micro.client", fmt.Sprintf("incomplete grpc-web frame: %v", err), http.StatusBadGateway)
+		}
+		switch {
+		case hdr[0]&grpcWebTrailerFrame != 0:
+			tr, err := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n")))).ReadMIMEHeader()
+			if err != nil && err != io.EOF {
+				return errors.InternalServerError("go.micro.client", fmt.Sprintf("invalid grpc-web trailer: %v", err))
+			}
+			for k, v := range tr {
+				trailer[k] = v
+			}
+		case hdr[0]&grpcWebCompressedFrame != 0:
+			return errors.InternalServerError("go.micro.client", "compressed grpc-web frames not supported")
+		case msg == nil:
+			msg = data
+		}
+	}
+
+	status, message := trailer.Get("Grpc-Status"), trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = hrsp.Header.Get("Grpc-Status"), hrsp.Header.Get("Grpc-Message")
+	}
+	if status != "" && status != "0" {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return errors.InternalServerError("go.micro.client", fmt.Sprintf("invalid grpc-status %q", status))
+		}
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return responseError(service, grpcHTTPStatus(code), []byte(message))
+	}
+
+	hrsp.Body = ioutil.NopCloser(bytes.NewReader(msg))
+	if msg == nil {
+		hrsp.Body = http.NoBody
+	}
+	// message decoded by request codec
+	hrsp.Header.Del("Content-Type")
+	return nil
+}
+
+// grpcHTTPStatus maps grpc status code to http status
+func grpcHTTPStatus(code int) int {
+	switch code {
+	case 1: // Canceled
+		return http.StatusRequestTimeout
+	case 3, 9, 11: // InvalidArgument, FailedPrecondition, OutOfRange
+		return http.StatusBadRequest
+	case 4: // DeadlineExceeded
+		return http.StatusGatewayTimeout
+	case 5: // NotFound
+		return http.StatusNotFound
+	case 6, 10: // AlreadyExists, Aborted
+		return http.StatusConflict
+	case 7: // PermissionDenied
+		return http.StatusForbidden
+	case 8: // ResourceExhausted
+		return http.StatusTooManyRequests
+	case 12: // Unimplemented
+		return http.StatusNotImplemented
+	case 14: // Unavailable
+		return http.StatusServiceUnavailable
+	case 16: // Unauthenticated
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
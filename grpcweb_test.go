@@ -0,0 +1,65 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+func TestGRPCWeb(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/grpc-web+json" || len(buf) < 5 || int(binary.BigEndian.Uint32(buf[1:5])) != len(buf)-5 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc-web+json")
+		switch r.URL.Path {
+		case "/not-found":
+			// trailers-only response
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "user%20not%20found")
+		case "/huge":
+			// length prefix without data must not be allocated
+			_, _ = w.Write([]byte{0, 0xff, 0xff, 0xff, 0xf0})
+		case "/failed":
+			_, _ = w.Write(grpcWebFrame([]byte(`{}`)))
+			tr := []byte("grpc-status: 14\r\ngrpc-message: unavailable\r\n")
+			_, _ = w.Write(append([]byte{grpcWebTrailerFrame, 0, 0, 0, byte(len(tr))}, tr...))
+		default:
+			_, _ = w.Write(grpcWebFrame([]byte(`{"name":"grpc"}`)))
+			tr := []byte("grpc-status: 0\r\n")
+			_, _ = w.Write(append([]byte{grpcWebTrailerFrame, 0, 0, 0, byte(len(tr))}, tr...))
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/ok", &Request{Name: "req"}), rsp, client.WithAddress(ts.URL), WithGRPCWeb()); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "grpc" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+
+	for path, code := range map[string]int32{"/not-found": 404, "/failed": 503} {
+		err := c.Call(context.TODO(), c.NewRequest("test", path, &Request{}), &Request{}, client.WithAddress(ts.URL), WithGRPCWeb())
+		if verr, ok := err.(*errors.Error); !ok || verr.Code != code || verr.Id != "test" {
+			t.Fatalf("%s: expected %d error, got %v", path, code, err)
+		}
+	}
+
+	err := c.Call(context.TODO(), c.NewRequest("test", "/huge", &Request{}), &Request{}, client.WithAddress(ts.URL), WithGRPCWeb())
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 502 {
+		t.Fatalf("expected 502 error for oversized frame, got %v", err)
+	}
+}
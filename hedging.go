@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/errors"
+	"go.unistack.org/micro/v3/selector"
+)
+
+// ErrHedgedCallCanceled recorded to selector for node which call canceled because
+// other node of hedged call responded first
+var ErrHedgedCallCanceled = errors.New("go.micro.client", "hedged call canceled", 499)
+
+type hedgedResult struct {
+	err     error
+	rsp     interface{}
+	commit  func()
+	node    string
+	latency time.Duration
+}
+
+// hedgedOutKeys are call options with pointers filled by call, each hedged call gets own copy
+var hedgedOutKeys = []interface{}{
+	responseMetadataKey{},
+	responseStatusKey{},
+	responseTrailerKey{},
+	rawResponseKey{},
+	allowedMethodsKey{},
+	dryRunKey{},
+}
+
+// hedgedOptions returns options with own out pointers and func copying their values to caller ones
+func hedgedOptions(opts client.CallOptions) (client.CallOptions, func()) {
+	var commits []func()
+	ctx := opts.Context
+	for _, key := range hedgedOutKeys {
+		dst := reflect.ValueOf(ctx.Value(key))
+		if dst.Kind() != reflect.Ptr || dst.IsNil() {
+			continue
+		}
+		src := reflect.New(dst.Type().Elem())
+		ctx = context.WithValue(ctx, key, src.Interface())
+		commits = append(commits, func() {
+			dst.Elem().Set(src.Elem())
+		})
+	}
+	opts.Context = ctx
+	return opts, func() {
+		for _, fn := range commits {
+			fn()
+		}
+	}
+}
+
+// hedgedCall calls node and after delay calls next node, first successful response
+// copied to rsp and other call canceled, node that served response or last failed returned,
+// first call sent to session node if it present in routes
+func (h *httpClient) hedgedCall(ctx context.Context, hcall client.CallFunc, next selector.Next, req client.Request, rsp interface{}, opts client.CallOptions, delay time.Duration, session *Session, routes []string) (string, error) {
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan hedgedResult, 2)
+	launch := func(node string) {
+		nrsp := newResponse(rsp)
+		nopts, commit := hedgedOptions(opts)
+		go func() {
+			start := time.Now()
+			err := hcall(copyOutgoingMetadata(hctx), node, req, nrsp, nopts)
+			ch <- hedgedResult{node: node, rsp: nrsp, commit: commit, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	var first string
+	if session != nil {
+		first = session.pick(routes)
+	}
+	if first == "" {
+		first = next()
+	}
+	launch(first)
+	pending := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedged := false
+
+	var err error
+	var node string
+	var commit func()
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if !hedged {
+				hedged = true
+				// hedged call sent to other node only
+				for i := 0; i < len(routes); i++ {
+					if node := next(); node != first {
+						launch(node)
+						pending++
+						break
+					}
+				}
+			}
+		case res := <-ch:
+			pending--
+			// record the result of the call to inform future routing decisions
+			if verr := h.record(res.node, res.latency, res.err); verr != nil {
+				return res.node, verr
+			}
+			if session != nil {
+				session.update(res.node, res.err)
+			}
+			if res.err == nil {
+				reflect.ValueOf(rsp).Elem().Set(reflect.ValueOf(res.rsp).Elem())
+				res.commit()
+				if pending > 0 {
+					// loser canceled on return, its latency is lower bound of node latency
+					go func() {
+						res := <-ch
+						_ = h.record(res.node, res.latency, ErrHedgedCallCanceled)
+					}()
+				}
+				return res.node, nil
+			}
+			node, err, commit = res.node, res.err, res.commit
+			if pending == 0 && !hedged {
+				commit()
+				// first call failed before delay, let retry handle it
+				return node, err
+			}
+		}
+	}
+
+	commit()
+	return node, err
+}
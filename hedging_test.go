@@ -0,0 +1,189 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/metadata"
+	"go.unistack.org/micro/v3/selector"
+)
+
+// sequenceSelector returns routes in order and records results
+type sequenceSelector struct {
+	records map[string][]error
+	sync.Mutex
+}
+
+func (s *sequenceSelector) Select(routes []string, opts ...selector.SelectOption) (selector.Next, error) {
+	var mu sync.Mutex
+	var idx int
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		route := routes[idx%len(routes)]
+		idx++
+		return route
+	}, nil
+}
+
+func (s *sequenceSelector) Record(route string, err error) error {
+	s.Lock()
+	s.records[route] = append(s.records[route], err)
+	s.Unlock()
+	return nil
+}
+
+func (s *sequenceSelector) Reset() error {
+	return nil
+}
+
+func (s *sequenceSelector) String() string {
+	return "sequence"
+}
+
+func TestHedging(t *testing.T) {
+	canceled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"fast"}`))
+	}))
+	defer fast.Close()
+
+	sel := &sequenceSelector{records: make(map[string][]error)}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(sel))
+
+	start := time.Now()
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp,
+		client.WithAddress(slow.URL, fast.URL), WithHedging(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "fast" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("hedged call took %v", d)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow request not canceled")
+	}
+
+	// loser recorded after its call returned
+	deadline := time.Now().Add(time.Second)
+	for {
+		sel.Lock()
+		errs := sel.records[slow.URL]
+		sel.Unlock()
+		if len(errs) == 1 && errs[0] == ErrHedgedCallCanceled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("canceled node must be recorded as canceled %v", errs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sel.Lock()
+	defer sel.Unlock()
+	if errs := sel.records[fast.URL]; len(errs) != 1 || errs[0] != nil {
+		t.Fatalf("fast node result not recorded %v", sel.records)
+	}
+}
+
+func TestHedgingSession(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits = append(hits, name)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"` + name + `"}`))
+		}
+	}
+	n1 := httptest.NewServer(handler("n1"))
+	defer n1.Close()
+	n2 := httptest.NewServer(handler("n2"))
+	defer n2.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(&roundRobinSelector{}))
+	s := NewSession()
+	for i := 0; i < 4; i++ {
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp,
+			client.WithAddress(n1.URL, n2.URL), WithHedging(time.Second), WithSession(s)); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != "n1" {
+			t.Fatalf("hedged call must keep session node, got %s", rsp.Name)
+		}
+	}
+	if s.Node() != n1.URL {
+		t.Fatalf("session node %s", s.Node())
+	}
+}
+
+func TestHedgingOutValues(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	handler := func(name string, d time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[name]++
+			mu.Unlock()
+			time.Sleep(d)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Node", name)
+			_, _ = w.Write([]byte(`{"name":"` + name + `"}`))
+		}
+	}
+	slow := httptest.NewServer(handler("slow", 200*time.Millisecond))
+	defer slow.Close()
+	fast := httptest.NewServer(handler("fast", 0))
+	defer fast.Close()
+
+	sel := &sequenceSelector{records: make(map[string][]error)}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(sel))
+
+	// out values filled by winner only
+	var md metadata.Metadata
+	var status int
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(slow.URL, fast.URL),
+		WithHedging(20*time.Millisecond), ResponseMetadata(&md), ResponseStatus(&status)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := md.Get("X-Node"); v != "fast" || status != http.StatusOK || rsp.Name != "fast" {
+		t.Fatalf("invalid winner values %s %d %s", v, status, rsp.Name)
+	}
+
+	// single node never hedged to itself
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(slow.URL),
+		WithHedging(20*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["slow"] != 2 {
+		t.Fatalf("expected 2 requests to slow node, got %d", hits["slow"])
+	}
+}
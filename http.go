@@ -4,67 +4,137 @@ package http // import "go.unistack.org/micro-client-http/v3"
 import (
 	"bufio"
 	"bytes"
+	"container/list"
 	"context"
+	"crypto/md5" // nolint: gosec
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.unistack.org/micro/v3/broker"
 	"go.unistack.org/micro/v3/client"
 	"go.unistack.org/micro/v3/codec"
 	"go.unistack.org/micro/v3/errors"
+	"go.unistack.org/micro/v3/logger"
 	"go.unistack.org/micro/v3/metadata"
 	"go.unistack.org/micro/v3/selector"
 	rutil "go.unistack.org/micro/v3/util/reflect"
+	"golang.org/x/net/http2"
 )
 
 var DefaultContentType = "application/json"
 
-/*
-func filterLabel(r []router.Route) []router.Route {
-	//				selector.FilterLabel("protocol", "http")
-	return r
-}
-*/
+// MetadataValueSeparator separates multiple values of outgoing metadata key,
+// each value sent as repeated header
+var MetadataValueSeparator = "\n"
 
 type httpClient struct {
+	// stats must be first to guarantee 64-bit alignment for atomic access
+	stats   connStats
 	httpcli *http.Client
+	limiter *hostLimiter
+	budget  *byteBudget
 	opts    client.Options
 	sync.RWMutex
 	init bool
+	// ownTransport true if http client created by us
+	ownTransport bool
+	// hostTransports transports with tls server name passed via WithHost,
+	// elements of hostLRU ordered by last use
+	hostTransports map[string]*list.Element
+	hostLRU        *list.List
+	trMu           sync.Mutex
+	// keyedRate limits request rate per key
+	keyedRate *keyedRateLimiter
+	// flight coalesces concurrent identical requests
+	flight *flightGroup
+	// adaptive limits in-flight requests by backend load signals
+	adaptive *adaptiveLimiter
+	// h2c transport for plain text requests if HTTP2 enabled
+	h2c *http2.Transport
+	// http2Err returned by Init if HTTP2 requested but not enabled
+	http2Err error
 }
 
-func newRequest(ctx context.Context, addr string, req client.Request, ct string, cf codec.Codec, msg interface{}, opts client.CallOptions) (*http.Request, error) {
+func (h *httpClient) newRequest(ctx context.Context, addr string, req client.Request, ct string, cf codec.Codec, msg interface{}, opts client.CallOptions) (*http.Request, error) {
 	var tags []string
 	var parameters map[string]map[string]string
 	scheme := "http"
-	method := http.MethodPost
+	method := h.requestMethod(req, opts)
 	body := "*" // as like google api http annotation
 	host := addr
-	path := req.Endpoint()
+	endpoint := req.Endpoint()
+
+	// pre-encoded body sent verbatim
+	var raw []byte
+	isRaw := true
+	switch v := msg.(type) {
+	case []byte:
+		raw = v
+	case *[]byte:
+		if v != nil {
+			raw = *v
+		}
+	case json.RawMessage:
+		raw = v
+		ct = jsonContentType(ct)
+	case *json.RawMessage:
+		if v != nil {
+			raw = *v
+		}
+		ct = jsonContentType(ct)
+	case *codec.Frame:
+		if v != nil {
+			raw = v.Data
+		}
+	default:
+		isRaw = false
+	}
+	if isRaw {
+		msg = nil
+	}
+
+	if fn, ok := h.opts.Context.Value(pathMapperKey{}).(func(string, string) (string, string)); ok && fn != nil {
+		if _, p := fn(req.Service(), req.Endpoint()); p != "" {
+			endpoint = p
+		}
+	}
+	var basePath string
 
 	u, err := url.Parse(addr)
 	if err == nil {
 		scheme = u.Scheme
-		path = u.Path
+		basePath = u.Path
 		host = u.Host
 	} else {
-		u = &url.URL{Scheme: scheme, Path: path, Host: host}
+		u = &url.URL{Scheme: scheme, Host: host}
+	}
+
+	// overrides precedence: call option > request option > default
+	rctx := requestContext(req)
+	pathSuffix, _ := rctx.Value(pathKey{}).(string)
+	if b, ok := rctx.Value(bodyKey{}).(string); ok {
+		body = b
 	}
 
 	// nolint: nestif
 	if opts.Context != nil {
-		if m, ok := opts.Context.Value(methodKey{}).(string); ok {
-			method = m
-		}
 		if p, ok := opts.Context.Value(pathKey{}).(string); ok {
-			path += p
+			pathSuffix = p
 		}
 		if b, ok := opts.Context.Value(bodyKey{}).(string); ok {
 			body = b
@@ -100,6 +170,16 @@ func newRequest(ctx context.Context, addr string, req client.Request, ct string,
 		}
 	}
 
+	if pathSuffix != "" {
+		endpoint = pathSuffix
+	}
+	var path string
+	if res, _ := h.opts.Context.Value(pathResolutionKey{}).(PathResolution); res == PathReference {
+		path = endpoint
+	} else {
+		path = joinPath(basePath, endpoint)
+	}
+
 	if len(tags) == 0 {
 		switch ct {
 		default:
@@ -109,37 +189,44 @@ func newRequest(ctx context.Context, addr string, req client.Request, ct string,
 		}
 	}
 
-	if path == "" {
-		path = req.Endpoint()
-	}
-
-	u, err = u.Parse(path)
-	if err != nil {
-		return nil, errors.BadRequest("go.micro.client", err.Error())
-	}
-
 	var nmsg interface{}
-	if len(u.Query()) > 0 {
-		path, nmsg, err = newPathRequest(u.Path+"?"+u.RawQuery, method, body, msg, tags, parameters)
+	if rawURL, ok := opts.Context.Value(urlKey{}).(string); ok && rawURL != "" {
+		// absolute url used as is, message sent as body
+		if u, err = url.Parse(rawURL); err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+		nmsg = msg
 	} else {
-		path, nmsg, err = newPathRequest(u.Path, method, body, msg, tags, parameters)
-	}
+		u, err = u.Parse(path)
+		if err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
 
-	if err != nil {
-		return nil, errors.BadRequest("go.micro.client", err.Error())
-	}
+		if len(u.Query()) > 0 {
+			path, nmsg, err = newPathRequest(u.Path+"?"+u.RawQuery, method, body, msg, tags, parameters)
+		} else {
+			path, nmsg, err = newPathRequest(u.Path, method, body, msg, tags, parameters)
+		}
 
-	u, err = url.Parse(fmt.Sprintf("%s://%s%s", scheme, host, path))
-	if err != nil {
-		return nil, errors.BadRequest("go.micro.client", err.Error())
+		if err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+
+		u, err = url.Parse(fmt.Sprintf("%s://%s%s", scheme, host, path))
+		if err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
 	}
 
 	var cookies []*http.Cookie
 	header := make(http.Header)
+	rawKeys, _ := h.opts.Context.Value(preserveHeaderCaseKey{}).(bool)
 	if opts.Context != nil {
 		if md, ok := opts.Context.Value(metadataKey{}).(metadata.Metadata); ok {
 			for k, v := range md {
-				header.Set(k, v)
+				if name, ok := h.headerName(k); ok {
+					setHeader(header, name, v, rawKeys)
+				}
 			}
 		}
 	}
@@ -149,16 +236,19 @@ func newRequest(ctx context.Context, addr string, req client.Request, ct string,
 
 	if md, ok := metadata.FromOutgoingContext(ctx); ok {
 		for k, v := range md {
-			header.Set(k, v)
+			if name, ok := h.headerName(k); ok {
+				setHeader(header, name, v, rawKeys)
+			}
 		}
 	}
 
-	// set timeout in nanoseconds
+	// set timeout in configured format, nanoseconds by default
+	tf, _ := h.opts.Context.Value(timeoutFormatKey{}).(TimeoutFormat)
 	if opts.StreamTimeout > time.Duration(0) {
-		header.Set(metadata.HeaderTimeout, fmt.Sprintf("%d", opts.StreamTimeout))
+		header.Set(metadata.HeaderTimeout, formatTimeout(opts.StreamTimeout, tf))
 	}
 	if opts.RequestTimeout > time.Duration(0) {
-		header.Set(metadata.HeaderTimeout, fmt.Sprintf("%d", opts.RequestTimeout))
+		header.Set(metadata.HeaderTimeout, formatTimeout(opts.RequestTimeout, tf))
 	}
 
 	// set the content type for the request
@@ -187,16 +277,114 @@ func newRequest(ctx context.Context, addr string, req client.Request, ct string,
 		}
 	}
 
-	b, err := cf.Marshal(nmsg)
-	if err != nil {
-		return nil, errors.BadRequest("go.micro.client", err.Error())
+	if c, ok := opts.Context.Value(callCookiesKey{}).([]*http.Cookie); ok {
+		cookies = append(cookies, c...)
 	}
 
 	var hreq *http.Request
+	var rc io.ReadCloser
+	if mf, ok := requestContext(req).Value(multipartFormKey{}).(multipartForm); ok {
+		var mct string
+		rc, mct = mf.body()
+		header.Set(metadata.HeaderContentType, mct)
+	} else if msgs := requestContext(req).Value(ndjsonKey{}); msgs != nil {
+		nb, nerr := newNDJSONBody(msgs)
+		if nerr != nil {
+			return nil, errors.BadRequest("go.micro.client", nerr.Error())
+		}
+		rc = nb.body(ctx, cf)
+		header.Set(metadata.HeaderContentType, NDJSONContentType)
+	} else if r, ok := requestContext(req).Value(bodyReaderKey{}).(io.Reader); ok && r != nil {
+		if rc, ok = r.(io.ReadCloser); !ok {
+			// hide known reader types, so request not gets content length
+			rc = ioutil.NopCloser(r)
+		}
+	}
+	maxSize, _ := opts.Context.Value(maxRequestSizeKey{}).(int64)
+	contentLength, hasContentLength := requestContext(req).Value(contentLengthKey{}).(int64)
+	if rc != nil {
+		if maxSize > 0 {
+			rc = &limitedBody{ReadCloser: rc, limit: maxSize}
+		}
+		hreq, err = http.NewRequestWithContext(ctx, method, u.String(), rc)
+		if err != nil {
+			_ = rc.Close()
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+		if hasContentLength && contentLength >= 0 {
+			hreq.ContentLength = contentLength
+		}
+		hreq.Header = header
+		for _, cookie := range cookies {
+			hreq.AddCookie(cookie)
+		}
+		if host, ok := opts.Context.Value(hostKey{}).(string); ok && host != "" {
+			hreq.Host = host
+		}
+		return hreq, nil
+	}
+
+	b := raw
+	// fields of GET, HEAD and OPTIONS requests sent in path and query, so no body
+	if !isRaw && !noBodyMethod(method) {
+		if b, err = cf.Marshal(nmsg); err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+	}
+
+	if layout, ok := opts.Context.Value(timeFormatKey{}).(string); ok && layout != "" && nmsg != nil && len(b) > 0 && isJSON(ct) {
+		if b, err = formatTimes(b, reflect.TypeOf(nmsg), layout); err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+	}
+
+	if v, ok := opts.Context.Value(grpcWebKey{}).(bool); ok && v {
+		// empty message still sent as frame
+		b = grpcWebFrame(b)
+		header.Set(metadata.HeaderContentType, grpcWebContentType(ct))
+		header.Set("Accept", grpcWebContentType(ct))
+		header.Set("X-Grpc-Web", "1")
+	}
+
+	if maxSize > 0 && int64(len(b)) > maxSize {
+		return nil, errors.BadRequest("go.micro.client", fmt.Sprintf("request body size %d exceeds limit %d", len(b), maxSize))
+	}
+
+	if v, ok := opts.Context.Value(contentMD5Key{}).(bool); ok && v && len(b) > 0 {
+		sum := md5.Sum(b)
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
 	if len(b) > 0 {
-		hreq, err = http.NewRequestWithContext(ctx, method, u.String(), ioutil.NopCloser(bytes.NewBuffer(b)))
-		hreq.ContentLength = int64(len(b))
-		header.Set("Content-Length", fmt.Sprintf("%d", hreq.ContentLength))
+		var rc io.ReadCloser = ioutil.NopCloser(bytes.NewBuffer(b))
+		if h.budget != nil {
+			// exact size acquired once, so waiting caller never holds part of budget,
+			// released after transport sent and closed body
+			n, berr := h.budget.Acquire(ctx, int64(len(b)))
+			if berr != nil {
+				return nil, errors.New("go.micro.client", fmt.Sprintf("%v", berr), 408)
+			}
+			rc = &releaseBody{ReadCloser: rc, release: []func(){func() { h.budget.Release(n) }}}
+		}
+		hreq, err = http.NewRequestWithContext(ctx, method, u.String(), rc)
+		if err != nil {
+			_ = rc.Close()
+		} else {
+			hreq.ContentLength = int64(len(b))
+			if hasContentLength {
+				// zero length with body means unknown length, so body sent chunked
+				hreq.ContentLength = 0
+				if contentLength >= 0 {
+					hreq.ContentLength = contentLength
+				}
+			}
+			if hreq.ContentLength > 0 {
+				header.Set("Content-Length", fmt.Sprintf("%d", hreq.ContentLength))
+			}
+			hreq.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(b)), nil
+			}
+		}
 	} else {
 		hreq, err = http.NewRequestWithContext(ctx, method, u.String(), nil)
 	}
@@ -209,11 +397,72 @@ func newRequest(ctx context.Context, addr string, req client.Request, ct string,
 	for _, cookie := range cookies {
 		hreq.AddCookie(cookie)
 	}
+	if host, ok := opts.Context.Value(hostKey{}).(string); ok && host != "" {
+		hreq.Host = host
+	}
 
 	return hreq, nil
 }
 
-func (h *httpClient) call(ctx context.Context, addr string, req client.Request, rsp interface{}, opts client.CallOptions) error {
+// headerName returns header name for metadata key, false means key not sent,
+// metadata filter applied to key before header mapper
+func (h *httpClient) headerName(k string) (string, bool) {
+	if mf, ok := h.opts.Context.Value(metadataFilterKey{}).(metadataFilter); ok && !mf.allowed(k) {
+		return "", false
+	}
+	if fn, ok := h.opts.Context.Value(headerMapperKey{}).(func(string) (string, bool)); ok && fn != nil {
+		name, send := fn(k)
+		return name, send && name != ""
+	}
+	return k, true
+}
+
+// requestMethod returns request http method,
+// precedence: call option > request option > path mapper > POST
+func (h *httpClient) requestMethod(req client.Request, opts client.CallOptions) string {
+	if opts.Context != nil {
+		if m, ok := opts.Context.Value(methodKey{}).(string); ok {
+			return m
+		}
+	}
+	if m, ok := requestContext(req).Value(methodKey{}).(string); ok {
+		return m
+	}
+	if fn, ok := h.opts.Context.Value(pathMapperKey{}).(func(string, string) (string, string)); ok && fn != nil {
+		if m, _ := fn(req.Service(), req.Endpoint()); m != "" {
+			return m
+		}
+	}
+	return http.MethodPost
+}
+
+// idempotent checks that request can be safely retried
+func (h *httpClient) idempotent(req client.Request, opts client.CallOptions) bool {
+	if v, ok := requestContext(req).Value(idempotentKey{}).(bool); ok {
+		return v
+	}
+	switch h.requestMethod(req, opts) {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func (h *httpClient) call(ctx context.Context, addr string, req client.Request, rsp interface{}, opts client.CallOptions) (err error) {
+	accept, fallback := opts.Context.Value(acceptKey{}).(string)
+	// repeated call result reported by first call
+	nested, _ := opts.Context.Value(nestedCallKey{}).(bool)
+
+	if cb, ok := h.opts.Context.Value(circuitBreakerKey{}).(CircuitBreaker); ok && cb != nil && !nested {
+		endpoint := breakerEndpoint(req)
+		if !cb.Allow(endpoint) {
+			return errors.New("go.micro.client", fmt.Sprintf("circuit breaker is open for %s", endpoint), 503)
+		}
+		defer func() {
+			cb.Report(endpoint, err)
+		}()
+	}
+
 	ct := req.ContentType()
 	if len(opts.ContentType) > 0 {
 		ct = opts.ContentType
@@ -223,30 +472,361 @@ func (h *httpClient) call(ctx context.Context, addr string, req client.Request,
 	if err != nil {
 		return errors.InternalServerError("go.micro.client", err.Error())
 	}
-	hreq, err := newRequest(ctx, addr, req, ct, cf, req.Body(), opts)
+
+	if vals, ok := opts.Context.Value(callValuesKey{}).([]callValue); ok {
+		for _, v := range vals {
+			ctx = context.WithValue(ctx, v.key, v.val)
+		}
+	}
+
+	hreq, err := h.newRequest(ctx, addr, req, ct, cf, req.Body(), opts)
 	if err != nil {
 		return err
 	}
 
+	// body closed on return before request sent, so streamed body writer released
+	sent := false
+	defer func() {
+		if !sent {
+			closeBody(hreq)
+		}
+	}()
+
+	// connection closed after response instead of returning to idle pool
+	if v, ok := requestContext(req).Value(closeKey{}).(bool); ok {
+		hreq.Close = v
+	}
+
+	if name, ok := h.opts.Context.Value(requestIDHeaderKey{}).(string); ok && name != "" && hreq.Header.Get(name) == "" {
+		id, uerr := newUUID()
+		if uerr != nil {
+			return errors.InternalServerError("go.micro.client", uerr.Error())
+		}
+		hreq.Header.Set(name, id)
+	}
+
+	if dh, ok := h.opts.Context.Value(deadlineHeaderKey{}).(deadlineHeader); ok {
+		if d, ok := ctx.Deadline(); ok {
+			hreq.Header.Set(dh.name, formatDeadline(d, dh.format))
+		}
+	}
+
+	if fallback {
+		hreq.Header.Set("Accept", accept)
+	} else if hreq.Header.Get("Accept") == "" {
+		hreq.Header.Set("Accept", h.accept())
+	}
+
+	if signer, ok := h.opts.Context.Value(requestSignerKey{}).(RequestSigner); ok && signer != nil {
+		if serr := signRequest(signer, hreq); serr != nil {
+			return errors.InternalServerError("go.micro.client", serr.Error())
+		}
+	}
+
+	if dr, ok := opts.Context.Value(dryRunKey{}).(*http.Request); ok && dr != nil {
+		// request not sent, caller owns body
+		sent = true
+		*dr = *hreq
+		return nil
+	}
+
+	var hrsp *http.Response
+	var snippet *snippetBody
+	if rl, ok := h.opts.Context.Value(requestLoggerKey{}).(requestLogger); ok {
+		l := rl.logger
+		if l == nil {
+			l = h.opts.Logger
+		}
+		if v, ok := h.opts.Context.Value(logBodyKey{}).(bool); ok && v {
+			snippet = &snippetBody{limit: DefaultLogBodySize}
+		}
+		start := time.Now()
+		defer func() {
+			logCall(ctx, l, hreq, hrsp, time.Since(start), snippet, err)
+		}()
+	}
+
+	// release funcs called after response processed
+	var release []func()
+	defer func() {
+		for _, fn := range release {
+			fn()
+		}
+	}()
+
+	if rerr := h.waitRate(ctx, req); rerr != nil {
+		return rerr
+	}
+
+	// load signal of backend, errors of client itself like limiter waits not counted
+	load := loadNone
+	if h.adaptive != nil && !nested {
+		gen, lerr := h.adaptive.Acquire(ctx)
+		if lerr != nil {
+			return errors.New("go.micro.client", fmt.Sprintf("%v", lerr), 408)
+		}
+		// result known only after response parsed
+		defer func() {
+			if load == loadOK && err != nil {
+				load = loadNone
+			}
+			h.adaptive.Release(gen, load)
+		}()
+	}
+
+	if h.limiter != nil {
+		priority, _ := opts.Context.Value(priorityKey{}).(int)
+		if lerr := h.limiter.Acquire(ctx, hreq.URL.Host, priority); lerr != nil {
+			return errors.New("go.micro.client", fmt.Sprintf("%v", lerr), 408)
+		}
+		host := hreq.URL.Host
+		release = append(release, func() { h.limiter.Release(host) })
+	}
+
+	var at *acquireTimer
+	if d, ok := opts.Context.Value(connAcquireTimeoutKey{}).(time.Duration); ok && d > 0 {
+		hreq, at = newAcquireTimer(hreq, d)
+		release = append(release, at.Close)
+	}
+
+	var ht *headerTimer
+	if d, ok := opts.Context.Value(responseHeaderTimeoutKey{}).(time.Duration); ok && d > 0 {
+		hreq, ht = newHeaderTimer(hreq, d)
+		release = append(release, ht.Close)
+	}
+
 	// make the request
-	hrsp, err := h.httpcli.Do(hreq)
+	hreq = h.stats.trace(hreq)
+
+	up, ok := opts.Context.Value(upgradeKey{}).(upgrade)
+	if ok && up.upgraded != nil {
+		hreq = up.trace(hreq)
+	}
+
+	// user trace composed with internal ones, request context deadline kept
+	if ct, ok := h.opts.Context.Value(clientTraceKey{}).(*httptrace.ClientTrace); ok && ct != nil {
+		hreq = hreq.WithContext(httptrace.WithClientTrace(hreq.Context(), ct))
+	}
+
+	httpcli := h.httpcli
+	if rt, ok := opts.Context.Value(roundTripperKey{}).(http.RoundTripper); ok && rt != nil {
+		cli := *h.httpcli
+		cli.Transport = rt
+		httpcli = &cli
+	} else if host, ok := opts.Context.Value(hostKey{}).(string); ok && host != "" && hreq.URL.Scheme == "https" {
+		// tls server name must match host
+		if tr := h.hostTransport(host); tr != nil {
+			cli := *h.httpcli
+			cli.Transport = tr
+			httpcli = &cli
+		}
+	}
+	if cache, ok := h.opts.Context.Value(responseCacheKey{}).(Cache); ok && cache != nil {
+		rt := httpcli.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		cli := *httpcli
+		cli.Transport = &cacheTransport{rt: rt, cache: cache}
+		httpcli = &cli
+	}
+	if h.flight != nil {
+		rt := httpcli.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		cli := *httpcli
+		cli.Transport = &flightTransport{rt: rt, group: h.flight}
+		httpcli = &cli
+	}
+	if wrappers, ok := h.opts.Context.Value(roundTripperWrappersKey{}).([]RoundTripperWrapper); ok && len(wrappers) > 0 {
+		rt := httpcli.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		for i := len(wrappers); i > 0; i-- {
+			rt = wrappers[i-1](rt)
+		}
+		cli := *httpcli
+		cli.Transport = rt
+		httpcli = &cli
+	}
+	// transport closes body
+	sent = true
+	hrsp, err = httpcli.Do(hreq)
 	if err != nil {
+		if at != nil && at.Fired() {
+			return ErrConnAcquireTimeout
+		}
+		if ht != nil && ht.Fired() {
+			load = loadOverload
+			return ErrResponseHeaderTimeout
+		}
 		switch err := err.(type) {
 		case *url.Error:
+			if err, ok := err.Err.(*errors.Error); ok {
+				return err
+			}
 			if err, ok := err.Err.(net.Error); ok && err.Timeout() {
+				load = loadOverload
 				return errors.Timeout("go.micro.client", err.Error())
 			}
 		case net.Error:
 			if err.Timeout() {
+				load = loadOverload
 				return errors.Timeout("go.micro.client", err.Error())
 			}
 		}
+		if verr := classifyNetError(err); verr != nil {
+			return verr
+		}
 		return errors.InternalServerError("go.micro.client", err.Error())
 	}
 
+	load = loadOf(hrsp.StatusCode)
+
+	if snippet != nil {
+		snippet.ReadCloser = hrsp.Body
+		hrsp.Body = snippet
+	}
+
+	if types, ok := opts.Context.Value(acceptFallbackKey{}).([]string); ok && len(types) > 0 && hrsp.StatusCode == http.StatusNotAcceptable && replayable(req) {
+		hrsp.Body.Close()
+		for _, fn := range release {
+			fn()
+		}
+		release = nil
+		// retry with next acceptable type, it used to decode response without content type
+		nopts := opts
+		nopts.Context = context.WithValue(opts.Context, acceptFallbackKey{}, types[1:])
+		nopts.Context = context.WithValue(nopts.Context, acceptKey{}, types[0])
+		nopts.Context = context.WithValue(nopts.Context, nestedCallKey{}, true)
+		if _, ok := opts.Context.Value(assumeContentTypeKey{}).(string); !ok || fallback {
+			nopts.Context = context.WithValue(nopts.Context, assumeContentTypeKey{}, types[0])
+		}
+		return h.call(ctx, addr, req, rsp, nopts)
+	}
+
+	if cr, ok := opts.Context.Value(conflictRetryKey{}).(conflictRetry); ok && cr.fn != nil && cr.retries > 0 && hrsp.StatusCode == http.StatusConflict && replayable(req) {
+		hrsp.Body.Close()
+		for _, fn := range release {
+			fn()
+		}
+		release = nil
+		// caller re-reads resource and builds request with fresh version
+		nreq, cerr := cr.fn(ctx)
+		if cerr != nil {
+			return cerr
+		}
+		nopts := opts
+		nopts.Context = context.WithValue(opts.Context, conflictRetryKey{}, conflictRetry{fn: cr.fn, retries: cr.retries - 1})
+		nopts.Context = context.WithValue(nopts.Context, nestedCallKey{}, true)
+		return h.call(ctx, addr, nreq, rsp, nopts)
+	}
+
+	if up.upgraded != nil && hrsp.StatusCode == http.StatusSwitchingProtocols {
+		// caller owns connection
+		up.upgraded.Reader = bufio.NewReader(hrsp.Body)
+		up.upgraded.Response = hrsp
+		return nil
+	}
+
+	if raw, ok := opts.Context.Value(rawResponseKey{}).(**http.Response); ok && raw != nil {
+		// caller owns response body, so release resources after it closed
+		hrsp.Body = &releaseBody{ReadCloser: hrsp.Body, release: release}
+		release = nil
+		*raw = hrsp
+		return nil
+	}
+
 	defer hrsp.Body.Close()
 
-	return h.parseRsp(ctx, hrsp, rsp, opts)
+	if err = decompressBody(hrsp); err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	err = h.parseRsp(ctx, req, hrsp, cf, rsp, opts)
+
+	if md, ok := opts.Context.Value(responseTrailerKey{}).(*metadata.Metadata); ok && md != nil {
+		// trailers populated only after body fully read
+		_, _ = io.Copy(ioutil.Discard, hrsp.Body)
+		*md = metadata.New(len(hrsp.Trailer))
+		for k, v := range hrsp.Trailer {
+			md.Set(k, strings.Join(v, ", "))
+		}
+	}
+
+	return err
+}
+
+// Close releases idle connections of client transport,
+// it is no-op if http.Client passed via HTTPClient option
+func (h *httpClient) Close() error {
+	if h.ownTransport {
+		h.httpcli.CloseIdleConnections()
+	}
+	if h.h2c != nil {
+		h.h2c.CloseIdleConnections()
+	}
+	h.trMu.Lock()
+	if h.hostLRU != nil {
+		for e := h.hostLRU.Front(); e != nil; e = e.Next() {
+			e.Value.(*hostTransportEntry).tr.CloseIdleConnections()
+		}
+	}
+	h.trMu.Unlock()
+	return nil
+}
+
+type hostTransportEntry struct {
+	host string
+	tr   *http.Transport
+}
+
+// hostTransport returns copy of client transport with tls server name set to host
+func (h *httpClient) hostTransport(host string) *http.Transport {
+	tr, ok := h.httpcli.Transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+
+	h.trMu.Lock()
+	defer h.trMu.Unlock()
+
+	if e, ok := h.hostTransports[host]; ok {
+		h.hostLRU.MoveToFront(e)
+		return e.Value.(*hostTransportEntry).tr
+	}
+	if h.hostTransports == nil {
+		h.hostTransports = make(map[string]*list.Element)
+		h.hostLRU = list.New()
+	}
+
+	t := tr.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{} // nolint: gosec
+	}
+	t.TLSClientConfig.ServerName = host
+	h.hostTransports[host] = h.hostLRU.PushFront(&hostTransportEntry{host: host, tr: t})
+
+	// in-flight requests of evicted transport not affected, only idle conns closed
+	for DefaultMaxHostTransports > 0 && h.hostLRU.Len() > DefaultMaxHostTransports {
+		e := h.hostLRU.Back()
+		ht := h.hostLRU.Remove(e).(*hostTransportEntry)
+		delete(h.hostTransports, ht.host)
+		ht.tr.CloseIdleConnections()
+	}
+
+	return t
+}
+
+// Stats returns connection statistics accumulated across calls
+func (h *httpClient) Stats() ClientStats {
+	return h.stats.stats()
 }
 
 func (h *httpClient) stream(ctx context.Context, addr string, req client.Request, opts client.CallOptions) (client.Stream, error) {
@@ -261,12 +841,38 @@ func (h *httpClient) stream(ctx context.Context, addr string, req client.Request
 		return nil, errors.InternalServerError("go.micro.client", err.Error())
 	}
 
-	cc, err := (h.httpcli.Transport).(*http.Transport).DialContext(ctx, "tcp", addr)
+	if err = h.waitRate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// stream uses raw connection, so it needs dialer of transport
+	tr, ok := h.httpcli.Transport.(*http.Transport)
+	if !ok || tr.DialContext == nil {
+		return nil, errors.InternalServerError("go.micro.client", fmt.Sprintf("stream not supported by transport %T", h.httpcli.Transport))
+	}
+
+	cc, err := tr.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return nil, errors.InternalServerError("go.micro.client", fmt.Sprintf("Error dialing: %v", err))
 	}
 
+	// silent server must not block stream forever
+	idle := opts.RequestTimeout
+	if v, ok := h.opts.Context.Value(noRequestTimeoutKey{}).(bool); ok && v {
+		idle = 0
+	}
+	if v, ok := opts.Context.Value(noRequestTimeoutKey{}).(bool); ok && v {
+		idle = 0
+	}
+	if v, ok := h.opts.Context.Value(streamIdleTimeoutKey{}).(time.Duration); ok {
+		idle = v
+	}
+	if v, ok := opts.Context.Value(streamIdleTimeoutKey{}).(time.Duration); ok {
+		idle = v
+	}
+
 	return &httpStream{
+		client:  h,
 		address: addr,
 		context: ctx,
 		closed:  make(chan bool),
@@ -276,6 +882,7 @@ func (h *httpClient) stream(ctx context.Context, addr string, req client.Request
 		cf:      cf,
 		reader:  bufio.NewReader(cc),
 		request: req,
+		idle:    idle,
 	}, nil
 }
 
@@ -294,6 +901,28 @@ func (h *httpClient) newCodec(ct string) (codec.Codec, error) {
 	return nil, codec.ErrUnknownContentType
 }
 
+// checkCodec checks that codec for default content type registered
+func (h *httpClient) checkCodec() error {
+	if _, err := h.newCodec(h.opts.ContentType); err != nil {
+		return fmt.Errorf("no codec registered for content type %s, pass it via client.Codec option: %w", h.opts.ContentType, err)
+	}
+	return nil
+}
+
+// accept returns content types of all registered codecs
+func (h *httpClient) accept() string {
+	h.RLock()
+	defer h.RUnlock()
+
+	cts := make([]string, 0, len(h.opts.Codecs))
+	for ct := range h.opts.Codecs {
+		cts = append(cts, ct)
+	}
+	sort.Strings(cts)
+
+	return strings.Join(cts, ", ")
+}
+
 func (h *httpClient) Init(opts ...client.Option) error {
 	if len(opts) == 0 && h.init {
 		return nil
@@ -302,6 +931,18 @@ func (h *httpClient) Init(opts ...client.Option) error {
 		o(&h.opts)
 	}
 
+	if isDefaultLookup(h.opts.Lookup) {
+		h.opts.Lookup = h.lookupRoute
+	}
+
+	if err := h.checkCodec(); err != nil {
+		return err
+	}
+
+	if h.http2Err != nil {
+		return h.http2Err
+	}
+
 	if err := h.opts.Broker.Init(); err != nil {
 		return err
 	}
@@ -336,20 +977,43 @@ func (h *httpClient) NewRequest(service, method string, req interface{}, opts ..
 	return newHTTPRequest(service, method, req, h.opts.ContentType, opts...)
 }
 
-func (h *httpClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+func (h *httpClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) (err error) {
 	// make a copy of call opts
 	callOpts := h.opts.CallOptions
 	for _, opt := range opts {
 		opt(&callOpts)
 	}
 
+	if sc, ok := callOpts.Context.Value(slowCallKey{}).(slowCall); ok && sc.threshold > 0 && sc.handler != nil {
+		// stack taken only if call is slow, it shows where call goroutines blocked
+		t := time.AfterFunc(sc.threshold, func() {
+			sc.handler(stackDump())
+		})
+		defer t.Stop()
+	}
+
+	noTimeout, _ := h.opts.Context.Value(noRequestTimeoutKey{}).(bool)
+	if v, ok := callOpts.Context.Value(noRequestTimeoutKey{}).(bool); ok {
+		noTimeout = v
+	}
+
 	// check if we already have a deadline
 	d, ok := ctx.Deadline()
-	if !ok {
+	if !ok && noTimeout {
+		// no timeout passed to server too
+		callOpts.RequestTimeout = 0
+	} else if !ok {
 		var cancel context.CancelFunc
 		// no deadline so we create a new one
 		ctx, cancel = context.WithTimeout(ctx, callOpts.RequestTimeout)
-		defer cancel()
+		defer func() {
+			// raw response body read after return, so cancel after it closed
+			if raw, ok := callOpts.Context.Value(rawResponseKey{}).(**http.Response); ok && raw != nil && *raw != nil && err == nil {
+				(*raw).Body = &releaseBody{ReadCloser: (*raw).Body, release: []func(){cancel}}
+				return
+			}
+			cancel()
+		}()
 	} else {
 		// got a deadline so no need to setup context
 		// but we need to set the timeout we pass along
@@ -387,7 +1051,39 @@ func (h *httpClient) Call(ctx context.Context, req client.Request, rsp interface
 		callOpts.Address = []string{h.opts.Proxy}
 	}
 
+	// absolute url bypasses routing
+	if rawURL, ok := callOpts.Context.Value(urlKey{}).(string); ok && rawURL != "" {
+		u, uerr := url.Parse(rawURL)
+		if uerr != nil || u.Host == "" {
+			return errors.BadRequest("go.micro.client", fmt.Sprintf("invalid url %s", rawURL))
+		}
+		callOpts.Address = []string{u.Scheme + "://" + u.Host}
+	}
+
+	if res, ok := callOpts.Context.Value(broadcastKey{}).(*BroadcastResult); ok && res != nil {
+		return h.broadcast(ctx, hcall, req, rsp, callOpts, res)
+	}
+
 	var next selector.Next
+	var routes []string
+	session, _ := callOpts.Context.Value(sessionKey{}).(*Session)
+
+	// node may be stored by call running after Call returned on context done
+	var selected atomic.Value
+	if sn, ok := callOpts.Context.Value(selectedNodeKey{}).(*string); ok && sn != nil {
+		defer func() {
+			if node, ok := selected.Load().(string); ok {
+				*sn = node
+			}
+		}()
+	}
+
+	var attempts int
+	if n, ok := callOpts.Context.Value(attemptsKey{}).(*int); ok && n != nil {
+		defer func() {
+			*n = attempts
+		}()
+	}
 
 	// return errors.New("go.micro.client", "request timeout", 408)
 	call := func(i int) error {
@@ -399,13 +1095,18 @@ func (h *httpClient) Call(ctx context.Context, req client.Request, rsp interface
 
 		// only sleep if greater than 0
 		if t.Seconds() > 0 {
-			time.Sleep(t)
+			if err = sleepContext(ctx, t); err != nil {
+				return err
+			}
+		}
+
+		// select again on retry, so nodes marked as failed by selector are avoided
+		if reselect, ok := callOpts.Context.Value(reselectPerRetryKey{}).(bool); ok && reselect && i > 0 {
+			next = nil
 		}
 
 		if next == nil {
-			var routes []string
 			// lookup the route to send the reques to
-			// TODO apply any filtering here
 			routes, err = h.opts.Lookup(ctx, req, callOpts)
 			if err != nil {
 				return errors.InternalServerError("go.micro.client", err.Error())
@@ -418,14 +1119,32 @@ func (h *httpClient) Call(ctx context.Context, req client.Request, rsp interface
 			}
 		}
 
-		node := next()
+		// streamed body can't be sent by two calls
+		if delay, ok := callOpts.Context.Value(hedgingKey{}).(time.Duration); ok && delay > 0 && newResponse(rsp) != nil && replayable(req) {
+			node, err := h.hedgedCall(ctx, hcall, next, req, rsp, callOpts, delay, session, routes)
+			selected.Store(node)
+			return err
+		}
+
+		var node string
+		if session != nil {
+			node = session.pick(routes)
+		}
+		if node == "" {
+			node = next()
+		}
+		selected.Store(node)
 
 		// make the call
-		err = hcall(ctx, node, req, rsp, callOpts)
+		start := time.Now()
+		err = hcall(copyOutgoingMetadata(ctx), node, req, rsp, callOpts)
 		// record the result of the call to inform future routing decisions
-		if verr := h.opts.Selector.Record(node, err); verr != nil {
+		if verr := h.record(node, time.Since(start), err); verr != nil {
 			return verr
 		}
+		if session != nil {
+			session.update(node, err)
+		}
 
 		// try and transform the error to a go-micro error
 		if verr, ok := err.(*errors.Error); ok {
@@ -435,34 +1154,65 @@ func (h *httpClient) Call(ctx context.Context, req client.Request, rsp interface
 		return err
 	}
 
-	ch := make(chan error, callOpts.Retries)
+	// retries and backoff may be overridden per call by client.WithRetries and client.WithBackoff,
+	// buffer fits all attempts, so call finished after context done not blocks
+	// single attempt without wrappers made synchronously, wrapper may block ignoring context
+	var ch chan error
+	if callOpts.Retries > 0 || len(callOpts.CallWrappers) > 0 {
+		ch = make(chan error, callOpts.Retries+1)
+	}
 	var gerr error
 
 	for i := 0; i <= callOpts.Retries; i++ {
-		go func() {
-			ch <- call(i)
-		}()
-
-		select {
-		case <-ctx.Done():
-			return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
-		case err := <-ch:
-			// if the call succeeded lets bail early
-			if err == nil {
-				return nil
+		attempts = i + 1
+		var err error
+		if ch == nil {
+			// single attempt made synchronously, request itself is bound to context
+			if err = call(i); err != nil && ctx.Err() != nil {
+				return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
 			}
-
-			retry, rerr := callOpts.Retry(ctx, req, i, err)
-			if rerr != nil {
-				return rerr
+		} else {
+			go func() {
+				ch <- call(i)
+			}()
+
+			select {
+			case <-ctx.Done():
+				return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
+			case err = <-ch:
 			}
+		}
 
-			if !retry {
-				return err
-			}
+		// if the call succeeded lets bail early
+		if err == nil {
+			return nil
+		}
+
+		retry, rerr := callOpts.Retry(ctx, req, i, err)
+		if rerr != nil {
+			return rerr
+		}
+
+		// streamed body already consumed by first attempt
+		if !retry || !replayable(req) {
+			return err
+		}
+
+		// non idempotent request may have side effects
+		if v, ok := h.opts.Context.Value(retryIdempotentOnlyKey{}).(bool); ok && v && !h.idempotent(req, callOpts) {
+			return err
+		}
+
+		gerr = err
+	}
 
-			gerr = err
+	// all retries exhausted
+	if name, ok := h.opts.Context.Value(retryExhaustionCounterKey{}).(string); ok && name != "" {
+		status := "unknown"
+		if verr, ok := gerr.(*errors.Error); ok {
+			status = fmt.Sprintf("%d", verr.Code)
 		}
+		h.opts.Meter.Counter(name, "method", req.Endpoint(), "status", status).Inc()
 	}
 
 	return gerr
@@ -533,13 +1283,19 @@ func (h *httpClient) Stream(ctx context.Context, req client.Request, opts ...cli
 
 		// only sleep if greater than 0
 		if t.Seconds() > 0 {
-			time.Sleep(t)
+			if cerr = sleepContext(ctx, t); cerr != nil {
+				return nil, cerr
+			}
+		}
+
+		// select again on retry, so nodes marked as failed by selector are avoided
+		if reselect, ok := callOpts.Context.Value(reselectPerRetryKey{}).(bool); ok && reselect && i > 0 {
+			next = nil
 		}
 
 		if next == nil {
 			var routes []string
 			// lookup the route to send the reques to
-			// TODO apply any filtering here
 			routes, err = h.opts.Lookup(ctx, req, callOpts)
 			if err != nil {
 				return nil, errors.InternalServerError("go.micro.client", err.Error())
@@ -554,10 +1310,11 @@ func (h *httpClient) Stream(ctx context.Context, req client.Request, opts ...cli
 
 		node := next()
 
+		start := time.Now()
 		stream, cerr := h.stream(ctx, node, req, callOpts)
 
 		// record the result of the call to inform future routing decisions
-		if verr := h.opts.Selector.Record(node, cerr); verr != nil {
+		if verr := h.record(node, time.Since(start), cerr); verr != nil {
 			return nil, verr
 		}
 
@@ -592,7 +1349,7 @@ func (h *httpClient) Stream(ctx context.Context, req client.Request, opts ...cli
 				return rsp.stream, nil
 			}
 
-			retry, rerr := callOpts.Retry(ctx, req, i, err)
+			retry, rerr := callOpts.Retry(ctx, req, i, rsp.err)
 			if rerr != nil {
 				return nil, rerr
 			}
@@ -621,6 +1378,18 @@ func (h *httpClient) publish(ctx context.Context, ps []client.Message, opts ...c
 
 	options := client.NewPublishOptions(opts...)
 
+	b := h.opts.Broker
+	webhook, _ := h.opts.Context.Value(publishWebhookKey{}).(func(string) string)
+	if options.Context != nil {
+		if pb, ok := options.Context.Value(publishBrokerKey{}).(publishBroker); ok {
+			if pb.broker == nil {
+				return errors.BadRequest("go.micro.client", "publish broker is nil")
+			}
+			b = pb.broker
+			webhook = nil
+		}
+	}
+
 	// get proxy
 	exchange := ""
 	if v, ok := os.LookupEnv("MICRO_PROXY"); ok {
@@ -667,12 +1436,68 @@ func (h *httpClient) publish(ctx context.Context, ps []client.Message, opts ...c
 		msgs = append(msgs, &broker.Message{Header: md, Body: body})
 	}
 
-	return h.opts.Broker.BatchPublish(ctx, msgs,
+	if webhook != nil {
+		var errmap map[string]interface{}
+		if options.Context != nil {
+			errmap, _ = options.Context.Value(errorMapKey{}).(map[string]interface{})
+		}
+		return h.publishWebhook(ctx, webhook, msgs, errmap)
+	}
+
+	return b.BatchPublish(ctx, msgs,
 		broker.PublishContext(ctx),
 		broker.PublishBodyOnly(options.BodyOnly),
 	)
 }
 
+// publishWebhook sends messages as http POST requests to url derived from topic, delivery confirmed
+// by 2xx response status, other status returned as error, its body decoded by error map if passed
+func (h *httpClient) publishWebhook(ctx context.Context, webhook func(string) string, msgs []*broker.Message, errmap map[string]interface{}) error {
+	for _, msg := range msgs {
+		topic, _ := msg.Header.Get(metadata.HeaderTopic)
+		hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook(topic), bytes.NewReader(msg.Body))
+		if err != nil {
+			return errors.BadRequest("go.micro.client", err.Error())
+		}
+		rawKeys, _ := h.opts.Context.Value(preserveHeaderCaseKey{}).(bool)
+		for k, v := range msg.Header {
+			setHeader(hreq.Header, k, v, rawKeys)
+		}
+
+		hrsp, err := h.httpcli.Do(hreq)
+		if err != nil {
+			return errors.InternalServerError("go.micro.client", err.Error())
+		}
+		buf, err := io.ReadAll(hrsp.Body)
+		hrsp.Body.Close()
+		if err != nil {
+			return errors.InternalServerError("go.micro.client", err.Error())
+		}
+		if hrsp.StatusCode >= 200 && hrsp.StatusCode < 300 {
+			continue
+		}
+		rerr, ok := errmap[fmt.Sprintf("%d", hrsp.StatusCode)]
+		if !ok {
+			rerr, ok = errmap["default"]
+		}
+		if !ok || rerr == nil {
+			return responseError(topic, hrsp.StatusCode, buf)
+		}
+		// error decoded by response content type, fallback to message content type
+		ct := hrsp.Header.Get(metadata.HeaderContentType)
+		if ct == "" {
+			ct, _ = msg.Header.Get(metadata.HeaderContentType)
+		}
+		cf, cerr := h.newCodec(ct)
+		if cerr != nil {
+			return responseError(topic, hrsp.StatusCode, buf)
+		}
+		return mappedError(topic, hrsp.StatusCode, buf, cf, rerr)
+	}
+
+	return nil
+}
+
 func (h *httpClient) String() string {
 	return "http"
 }
@@ -692,26 +1517,83 @@ func NewClient(opts ...client.Option) client.Client {
 		opts: options,
 	}
 
+	// filter routes returned by router unless lookup provided by caller
+	if isDefaultLookup(options.Lookup) {
+		rc.opts.Lookup = rc.lookupRoute
+	}
+
+	if n, ok := options.Context.Value(maxConcurrentPerHostKey{}).(int); ok && n > 0 {
+		rc.limiter = newHostLimiter(n)
+	}
+
+	if n, ok := options.Context.Value(bodyBufferBudgetKey{}).(int64); ok && n > 0 {
+		rc.budget = newByteBudget(n)
+	}
+
+	// fail fast, otherwise error returned only on first call
+	if err := rc.checkCodec(); err != nil && options.Logger.V(logger.ErrorLevel) {
+		options.Logger.Errorf(options.Context, "%v", err)
+	}
+
+	if kr, ok := options.Context.Value(keyedRateLimiterKey{}).(keyedRateLimiterOption); ok && kr.key != nil && kr.newLimiter != nil {
+		rc.keyedRate = newKeyedRateLimiter(kr.key, kr.newLimiter)
+	}
+
+	if ac, ok := options.Context.Value(adaptiveConcurrencyKey{}).(adaptiveConcurrency); ok && ac.max > 0 {
+		rc.adaptive = newAdaptiveLimiter(ac.min, ac.max)
+	}
+
+	if v, ok := options.Context.Value(singleFlightKey{}).(bool); ok && v {
+		key, _ := options.Context.Value(singleFlightKeyFuncKey{}).(func(*http.Request) string)
+		// generated request id and deadline differ for each call
+		var skip []string
+		if name, ok := options.Context.Value(requestIDHeaderKey{}).(string); ok {
+			skip = append(skip, name)
+		}
+		if dh, ok := options.Context.Value(deadlineHeaderKey{}).(deadlineHeader); ok {
+			skip = append(skip, dh.name)
+		}
+		rc.flight = newFlightGroup(key, skip...)
+	}
+
+	keepAlive, hasKeepAlive := options.Context.Value(keepAliveKey{}).(time.Duration)
+
 	var dialer func(context.Context, string) (net.Conn, error)
 	if v, ok := options.Context.Value(httpDialerKey{}).(*net.Dialer); ok {
+		if hasKeepAlive {
+			// caller dialer not modified
+			d := *v
+			d.KeepAlive = keepAlive
+			v = &d
+		}
 		dialer = func(ctx context.Context, addr string) (net.Conn, error) {
 			return v.DialContext(ctx, "tcp", addr)
 		}
 	}
 	if options.ContextDialer != nil {
 		dialer = options.ContextDialer
+		if hasKeepAlive {
+			dialer = keepAliveDialer(dialer, keepAlive)
+		}
 	}
 	if dialer == nil {
+		d := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		if hasKeepAlive {
+			d.KeepAlive = keepAlive
+		}
 		dialer = func(ctx context.Context, addr string) (net.Conn, error) {
-			return (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext(ctx, "tcp", addr)
+			return d.DialContext(ctx, "tcp", addr)
 		}
 	}
 
 	if httpcli, ok := options.Context.Value(httpClientKey{}).(*http.Client); ok {
 		rc.httpcli = httpcli
+		if v, ok := options.Context.Value(http2Key{}).(bool); ok && v {
+			rc.http2Err = fmt.Errorf("failed to enable http2: transport of http client passed via HTTPClient option not modified")
+		}
 	} else {
 		// TODO customTransport := http.DefaultTransport.(*http.Transport).Clone()
 		tr := &http.Transport{
@@ -727,7 +1609,45 @@ func NewClient(opts ...client.Option) client.Client {
 			ExpectContinueTimeout: 1 * time.Second,
 			TLSClientConfig:       options.TLSConfig,
 		}
+		if n, ok := options.Context.Value(maxIdleConnsKey{}).(int); ok {
+			tr.MaxIdleConns = n
+		}
+		if n, ok := options.Context.Value(maxIdleConnsPerHostKey{}).(int); ok {
+			tr.MaxIdleConnsPerHost = n
+		}
+		if d, ok := options.Context.Value(idleConnTimeoutKey{}).(time.Duration); ok {
+			tr.IdleConnTimeout = d
+		}
+		if d, ok := options.Context.Value(responseHeaderTimeoutKey{}).(time.Duration); ok {
+			tr.ResponseHeaderTimeout = d
+		}
+		if p, ok := options.Context.Value(proxyKey{}).(string); ok && p != "" {
+			if pu, err := url.Parse(p); err != nil {
+				if options.Logger.V(logger.ErrorLevel) {
+					options.Logger.Errorf(options.Context, "invalid proxy url %s: %v", p, err)
+				}
+			} else {
+				tr.Proxy = http.ProxyURL(pu)
+			}
+		}
+		if v, ok := options.Context.Value(http2Key{}).(bool); ok && v {
+			if rc.h2c, rc.http2Err = configureHTTP2(tr); rc.http2Err != nil {
+				rc.http2Err = fmt.Errorf("failed to enable http2: %w", rc.http2Err)
+			}
+		}
 		rc.httpcli = &http.Client{Transport: tr}
+		rc.ownTransport = true
+		if jar, ok := options.Context.Value(cookieJarKey{}).(http.CookieJar); ok {
+			rc.httpcli.Jar = jar
+		}
+		if n, ok := options.Context.Value(maxRedirectsKey{}).(int); ok {
+			rc.httpcli.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				if len(via) >= n {
+					return errors.New("go.micro.client", fmt.Sprintf("too many redirects, stopped after %d", len(via)), http.StatusLoopDetected)
+				}
+				return nil
+			}
+		}
 	}
 	c := client.Client(rc)
 
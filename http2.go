@@ -0,0 +1,35 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// configureHTTP2 enables http2 with prior knowledge for plain text connections (h2c),
+// tls connections negotiate http2 or http/1.1 via alpn, returns h2c transport
+func configureHTTP2(tr *http.Transport) (t *http2.Transport, err error) {
+	dial := tr.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	h2c := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+		DisableCompression: tr.DisableCompression,
+	}
+	// RegisterProtocol panics if scheme already registered
+	defer func() {
+		if r := recover(); r != nil {
+			t, err = nil, fmt.Errorf("failed to register h2c transport: %v", r)
+		}
+	}()
+	tr.RegisterProtocol("http", h2c)
+	return h2c, nil
+}
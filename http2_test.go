@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestHTTP2PriorKnowledge(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	ts := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), HTTP2(true))
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	rsp := make(map[string]interface{})
+	req := c.NewRequest("test", "/test", &Request{})
+	if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	// tls peer without http2 support still served via http/1.1
+	tls := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	tls.StartTLS()
+	defer tls.Close()
+
+	tr := c.(*httpClient).httpcli.Transport.(*http.Transport)
+	tr.TLSClientConfig = tls.Client().Transport.(*http.Transport).TLSClientConfig
+	if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(tls.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	// option can not be applied to transport of caller http client
+	c = NewClient(client.Codec("application/json", codec.NewCodec()), HTTP2(true), HTTPClient(&http.Client{}))
+	if err := c.Init(); err == nil {
+		t.Fatal("init must fail if http2 not enabled")
+	}
+}
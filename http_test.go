@@ -1,9 +1,30 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/broker"
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+	"go.unistack.org/micro/v3/metadata"
+	"go.unistack.org/micro/v3/selector"
 )
 
 type Request struct {
@@ -79,3 +100,1804 @@ func TestInvalidPath(t *testing.T) {
 		t.Fatal("path param must not be filled")
 	}
 }
+
+func TestRequestIDHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), RequestIDHeader("X-Request-Id"))
+
+	var md metadata.Metadata
+	rsp := make(map[string]interface{})
+	req := c.NewRequest("test", "/test", &Request{Name: "vtolstov"})
+	if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL), ResponseMetadata(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := md.Get("X-Request-Id"); !ok || len(id) != 36 {
+		t.Fatalf("invalid request id %q", id)
+	}
+
+	md = nil
+	ctx := metadata.NewOutgoingContext(context.TODO(), metadata.Metadata{"X-Request-Id": "caller-id"})
+	if err := c.Call(ctx, req, &rsp, client.WithAddress(ts.URL), ResponseMetadata(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := md.Get("X-Request-Id"); id != "caller-id" {
+		t.Fatalf("request id must be passed from caller, got %q", id)
+	}
+}
+
+func TestSlowCallThreshold(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	dumps := make(chan []byte, 1)
+	rsp := make(map[string]interface{})
+	req := c.NewRequest("test", "/test", &Request{Name: "vtolstov"})
+	err := c.Call(context.TODO(), req, &rsp,
+		client.WithAddress(ts.URL),
+		WithSlowCallThreshold(20*time.Millisecond, func(dump []byte) { dumps <- dump }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case dump := <-dumps:
+		// dump taken while call blocked
+		if !strings.Contains(string(dump), "TestSlowCallThreshold") || !strings.Contains(string(dump), "net/http") {
+			t.Fatalf("invalid stack dump: %s", dump)
+		}
+	default:
+		t.Fatal("slow call handler not invoked")
+	}
+}
+
+func TestResponseCodecNegotiation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "application/json, application/x-ndjson" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		_, _ = w.Write([]byte(`{"name":"vtolstov"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		client.Codec("application/x-ndjson", codec.NewCodec()),
+	)
+
+	rsp := &Request{}
+	req := c.NewRequest("test", "/test", &Request{Name: "vtolstov"})
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "vtolstov" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+type testBreaker struct {
+	reports map[string][]error
+	open    bool
+}
+
+func (b *testBreaker) Allow(endpoint string) bool {
+	return !b.open
+}
+
+func (b *testBreaker) Report(endpoint string, err error) {
+	b.reports[endpoint] = append(b.reports[endpoint], err)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	cb := &testBreaker{reports: make(map[string][]error)}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithCircuitBreaker(cb))
+
+	rsp := make(map[string]interface{})
+	req := c.NewRequest("test", "/test", &Request{Name: "vtolstov"})
+	if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := cb.reports["test/test"]; len(errs) != 1 || errs[0] != nil {
+		t.Fatalf("invalid breaker reports %v", cb.reports)
+	}
+
+	cb.open = true
+	err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL))
+	if merr, ok := err.(*errors.Error); !ok || merr.Code != 503 {
+		t.Fatalf("invalid error %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("request must not be sent with open breaker, calls %d", calls)
+	}
+}
+
+func TestBodyReader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		if r.ContentLength != -1 || len(r.TransferEncoding) == 0 || r.TransferEncoding[0] != "chunked" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"` + string(buf) + `"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	req := c.NewRequest("test", "/upload", nil, WithBodyReader(strings.NewReader("file content")))
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "file content" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+func TestBodyReaderNotRetried(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(2), client.Retry(client.RetryAlways))
+
+	req := c.NewRequest("test", "/upload", nil, WithBodyReader(strings.NewReader("file content")))
+	err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL), WithHedging(10*time.Millisecond))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected first attempt error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("streamed body must be sent once, got %d requests", n)
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`raw body`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var hrsp *http.Response
+	req := c.NewRequest("test", "/test", &Request{})
+	if err := c.Call(context.TODO(), req, nil, client.WithAddress(ts.URL), WithRawResponse(&hrsp)); err != nil {
+		t.Fatal(err)
+	}
+	defer hrsp.Body.Close()
+
+	buf, err := io.ReadAll(hrsp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hrsp.StatusCode != http.StatusAccepted || string(buf) != "raw body" {
+		t.Fatalf("invalid response %d %s", hrsp.StatusCode, buf)
+	}
+}
+
+type testBroker struct {
+	broker.Broker
+	msgs []*broker.Message
+}
+
+func (b *testBroker) BatchPublish(ctx context.Context, msgs []*broker.Message, opts ...broker.PublishOption) error {
+	b.msgs = append(b.msgs, msgs...)
+	return nil
+}
+
+func TestPublishBroker(t *testing.T) {
+	def := &testBroker{}
+	alt := &testBroker{}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Broker(def))
+
+	msg := c.NewMessage("topic", &codec.Frame{Data: []byte("data")})
+	if err := c.Publish(context.TODO(), msg, WithBroker(alt)); err != nil {
+		t.Fatal(err)
+	}
+	if len(alt.msgs) != 1 || len(def.msgs) != 0 {
+		t.Fatalf("message must be published to alternate broker")
+	}
+	if string(alt.msgs[0].Body) != "data" {
+		t.Fatalf("invalid message body %s", alt.msgs[0].Body)
+	}
+
+	if err := c.Publish(context.TODO(), msg, WithBroker(nil)); err == nil {
+		t.Fatal("nil broker must be rejected")
+	}
+}
+
+func TestPublishWebhook(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/events/topic" || r.Header.Get(metadata.HeaderTopic) != "topic" ||
+			r.Header.Get("Content-Type") != "application/json" || !strings.HasPrefix(string(buf), `{"name":"event"`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	def := &testBroker{}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Broker(def),
+		PublishWebhook(func(topic string) string { return ts.URL + "/events/" + topic }))
+
+	if err := c.Publish(context.TODO(), c.NewMessage("topic", &Request{Name: "event"})); err != nil {
+		t.Fatal(err)
+	}
+	if len(def.msgs) != 0 {
+		t.Fatal("message must not be published to broker")
+	}
+	if err := c.Publish(context.TODO(), c.NewMessage("other", &Request{Name: "event"})); err == nil {
+		t.Fatal("expected webhook error")
+	}
+}
+
+func TestTransportPoolOptions(t *testing.T) {
+	c := NewClient(MaxIdleConns(200), MaxIdleConnsPerHost(50), IdleConnTimeout(time.Minute))
+	tr := c.(*httpClient).httpcli.Transport.(*http.Transport)
+	if tr.MaxIdleConns != 200 || tr.MaxIdleConnsPerHost != 50 || tr.IdleConnTimeout != time.Minute {
+		t.Fatalf("invalid transport options %d %d %v", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+	}
+}
+
+func TestContentMD5(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(buf)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := make(map[string]interface{})
+	req := c.NewRequest("test", "/test", &Request{Name: "vtolstov"})
+	if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL), WithContentMD5()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamRetryError(t *testing.T) {
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var errs []error
+	retry := func(ctx context.Context, req client.Request, retryCount int, err error) (bool, error) {
+		errs = append(errs, err)
+		return false, nil
+	}
+
+	req := c.NewRequest("test", "/test", &Request{})
+	_, err := c.Stream(context.TODO(), req, client.WithAddress("127.0.0.1:1"), client.WithRetry(retry), client.WithRetries(1))
+	if err == nil {
+		t.Fatal("stream must fail")
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Fatalf("retry func must get attempt error, got %v", errs)
+	}
+}
+
+func TestMaxRedirects(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", hits), http.StatusFound)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithMaxRedirects(3))
+
+	rsp := make(map[string]interface{})
+	req := c.NewRequest("test", "/test", &Request{})
+	err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL))
+	if merr, ok := err.(*errors.Error); !ok || merr.Code != http.StatusLoopDetected {
+		t.Fatalf("invalid error %v", err)
+	}
+	if hits != 3 {
+		t.Fatalf("redirects must be stopped after 3 requests, got %d", hits)
+	}
+}
+
+func TestPathMapper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"` + r.Method + " " + r.URL.Path + `"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		PathMapper(func(service, endpoint string) (string, string) {
+			return http.MethodPut, "/" + service + "/" + strings.ToLower(strings.TrimPrefix(endpoint, "Users."))
+		}),
+	)
+
+	rsp := &Request{}
+	req := c.NewRequest("users", "Users.Update", &Request{})
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "PUT /users/update" {
+		t.Fatalf("invalid request %s", rsp.Name)
+	}
+
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), Method(http.MethodPatch)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "PATCH /users/update" {
+		t.Fatalf("method call option must override mapper, got %s", rsp.Name)
+	}
+}
+
+func TestWarningHandler(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Warning", `299 - "Deprecated API"`)
+		w.Header().Add("Warning", `110 - "Response is Stale"`)
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	for _, path := range []string{"/test", "/test?fail=1"} {
+		var warnings []string
+		rsp := &Request{}
+		err := c.Call(context.TODO(), c.NewRequest("test", path, &Request{}), rsp, client.WithAddress(ts.URL),
+			WithWarningHandler(func(w []string) { warnings = w }))
+		if (path == "/test") != (err == nil) {
+			t.Fatalf("%s: unexpected err %v", path, err)
+		}
+		if len(warnings) != 2 || warnings[0] != `299 - "Deprecated API"` || warnings[1] != `110 - "Response is Stale"` {
+			t.Fatalf("%s: invalid warnings %v", path, warnings)
+		}
+	}
+}
+
+func TestCookies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret", Path: "/"})
+		case "/test":
+			if c, err := r.Cookie("session"); err != nil || c.Value != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+			} else if c, err := r.Cookie("lang"); err != nil || c.Value != "en" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), EnableCookies())
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/login", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL),
+		WithCookie(&http.Cookie{Name: "lang", Value: "en"})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestRoundTripper(t *testing.T) {
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		buf, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if req.Header.Get("Content-Type") != "application/json" || !strings.Contains(string(buf), `"name":"mock"`) {
+			return nil, fmt.Errorf("invalid request %v %s", req.Header, buf)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"name":"canned"}`)),
+			Request:    req,
+		}, nil
+	})
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "mock"}), rsp,
+		client.WithAddress("http://127.0.0.1:1"), WithRoundTripper(rt)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "canned" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+func TestClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	type statsCloser interface {
+		Stats() ClientStats
+		Close() error
+	}
+
+	for _, owned := range []bool{true, false} {
+		opts := []client.Option{client.Codec("application/json", codec.NewCodec())}
+		if !owned {
+			opts = append(opts, HTTPClient(&http.Client{Transport: &http.Transport{}}))
+		}
+		c := NewClient(opts...)
+		sc := c.(statsCloser)
+
+		for i := 0; i < 2; i++ {
+			rsp := &Request{}
+			if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+				t.Fatal(err)
+			}
+			if err := sc.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stats := sc.Stats()
+		if owned && (stats.Dialed != 2 || stats.Reused != 0) {
+			t.Fatalf("idle connections not closed %#+v", stats)
+		} else if !owned && (stats.Dialed != 1 || stats.Reused != 1) {
+			t.Fatalf("user http client connections closed %#+v", stats)
+		}
+	}
+}
+
+type failReadCodec struct {
+	codec.Codec
+}
+
+func (c *failReadCodec) ReadBody(r io.Reader, v interface{}) error {
+	return fmt.Errorf("failReadCodec can't read body")
+}
+
+func TestAssumeContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Content-Type"] = nil
+		_, _ = w.Write([]byte(`{"name":"vtolstov"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		client.Codec("application/x-test", &failReadCodec{codec.NewCodec()}),
+	)
+
+	req := c.NewRequest("test", "/test", &Request{Name: "vtolstov"})
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), client.WithContentType("application/x-test")); err == nil {
+		t.Fatal("expected request codec used")
+	}
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), client.WithContentType("application/x-test"),
+		WithAssumeContentType("application/json")); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "vtolstov" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+// failoverSelector always selects first route not recorded as failed
+type failoverSelector struct {
+	failed map[string]bool
+}
+
+func (s *failoverSelector) Select(routes []string, opts ...selector.SelectOption) (selector.Next, error) {
+	var node string
+	for _, route := range routes {
+		if !s.failed[route] {
+			node = route
+			break
+		}
+	}
+	if node == "" {
+		return nil, selector.ErrNoneAvailable
+	}
+	return func() string { return node }, nil
+}
+
+func (s *failoverSelector) Record(route string, err error) error {
+	if err != nil {
+		s.failed[route] = true
+	}
+	return nil
+}
+
+func (s *failoverSelector) Reset() error {
+	s.failed = make(map[string]bool)
+	return nil
+}
+
+func (s *failoverSelector) String() string {
+	return "failover"
+}
+
+func TestReselectPerRetry(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	for _, reselect := range []bool{false, true} {
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(&failoverSelector{failed: make(map[string]bool)}))
+
+		opts := []client.CallOption{client.WithAddress(down.URL, ts.URL), client.WithRetries(2), client.WithRetry(client.RetryAlways)}
+		if reselect {
+			opts = append(opts, WithReselectPerRetry())
+		}
+
+		rsp := &Request{}
+		err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, opts...)
+		if reselect && err != nil {
+			t.Fatal(err)
+		} else if !reselect && err == nil {
+			t.Fatal("expected down node retried")
+		}
+	}
+}
+
+func TestMetadataIsolation(t *testing.T) {
+	var attempts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, r.Header.Get("X-Attempt"))
+		w.Header().Set("Content-Type", "application/json")
+		if len(attempts) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	// wrapper mutates outgoing metadata on each attempt
+	wrapper := func(fn client.CallFunc) client.CallFunc {
+		return func(ctx context.Context, addr string, req client.Request, rsp interface{}, opts client.CallOptions) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			v, _ := md.Get("X-Attempt")
+			md.Set("X-Attempt", v+"a")
+			return fn(ctx, addr, req, rsp, opts)
+		}
+	}
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	md := metadata.New(1)
+	md.Set("X-Request", "test")
+	ctx := metadata.NewOutgoingContext(context.TODO(), md)
+
+	rsp := &Request{}
+	if err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL),
+		client.WithCallWrapper(wrapper), client.WithRetries(1), client.WithRetry(client.RetryAlways)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 || attempts[0] != "a" || attempts[1] != "a" {
+		t.Fatalf("metadata leaked across attempts %v", attempts)
+	}
+	if _, ok := md.Get("X-Attempt"); ok || len(md) != 1 {
+		t.Fatalf("caller metadata modified %v", md)
+	}
+}
+
+func TestResponseTrailer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		_, _ = w.Write([]byte(`{"name":"vtolstov"}`))
+		_, _ = w.Write([]byte("\n\n"))
+		w.Header().Set("Grpc-Status", "5")
+		w.Header().Set("Grpc-Message", "not found")
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var md metadata.Metadata
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL), ResponseTrailer(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := md.Get("Grpc-Status"); v != "5" {
+		t.Fatalf("invalid trailers %v", md)
+	}
+	if v, _ := md.Get("Grpc-Message"); v != "not found" {
+		t.Fatalf("invalid trailers %v", md)
+	}
+}
+
+func TestMaxRequestSize(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	req := c.NewRequest("test", "/test", &Request{Name: strings.Repeat("a", 100)})
+	err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), WithMaxRequestSize(64))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %v", err)
+	}
+	if requests != 0 {
+		t.Fatal("oversized request must not be sent")
+	}
+
+	if err = c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), WithMaxRequestSize(1024)); err != nil {
+		t.Fatal(err)
+	}
+
+	req = c.NewRequest("test", "/test", nil, WithBodyReader(strings.NewReader(strings.Repeat("a", 100))))
+	err = c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), WithMaxRequestSize(64), client.WithRetries(0))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for streamed body, got %v", err)
+	}
+}
+
+func TestFrameResponse(t *testing.T) {
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", &failReadCodec{codec.NewCodec()}))
+
+	rsp := &codec.Frame{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rsp.Data, data) {
+		t.Fatalf("invalid frame data %v", rsp.Data)
+	}
+}
+
+func TestStatusRewriter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(299)
+		_, _ = w.Write([]byte(`{"name":"vtolstov"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var status int
+	rsp := &Request{}
+	err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL),
+		ResponseStatus(&status), WithStatusRewriter(func(code int) int {
+			if code == 299 {
+				return http.StatusOK
+			}
+			return code
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 299 || rsp.Name != "vtolstov" {
+		t.Fatalf("invalid status %d or response %#+v", status, rsp)
+	}
+
+	err = c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL),
+		WithStatusRewriter(func(code int) int { return http.StatusBadGateway }))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusBadGateway {
+		t.Fatalf("expected rewritten error status, got %v", err)
+	}
+}
+
+func TestTimeoutFormat(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.Header.Get(metadata.HeaderTimeout))
+	}))
+	defer ts.Close()
+
+	for format, expect := range map[TimeoutFormat]string{
+		TimeoutNanoseconds: "1500000000",
+		TimeoutSeconds:     "2",
+		TimeoutDuration:    "1.5s",
+	} {
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), WithTimeoutFormat(format))
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL),
+			client.WithRequestTimeout(1500*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != expect {
+			t.Fatalf("format %d: expected timeout %q, got %q", format, expect, rsp.Name)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q,"field1":%q}`, r.Host, r.TLS.ServerName)
+	}))
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.TLSConfig(&tls.Config{RootCAs: pool}))
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL), WithHost("example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "example.com" || rsp.Field1 != "example.com" {
+		t.Fatalf("invalid host %q or server name %q", rsp.Name, rsp.Field1)
+	}
+}
+
+func TestHostTransportsBounded(t *testing.T) {
+	defer func(n int) { DefaultMaxHostTransports = n }(DefaultMaxHostTransports)
+	DefaultMaxHostTransports = 2
+
+	hc := NewClient().(*httpClient)
+	a := hc.hostTransport("a.example.com")
+	hc.hostTransport("b.example.com")
+	if hc.hostTransport("a.example.com") != a {
+		t.Fatal("expected cached transport")
+	}
+	hc.hostTransport("c.example.com")
+
+	if len(hc.hostTransports) != 2 || hc.hostLRU.Len() != 2 {
+		t.Fatalf("expected 2 transports, got %d", len(hc.hostTransports))
+	}
+	if _, ok := hc.hostTransports["b.example.com"]; ok {
+		t.Fatal("expected least recently used transport evicted")
+	}
+	if _, ok := hc.hostTransports["a.example.com"]; !ok {
+		t.Fatal("expected recently used transport kept")
+	}
+}
+
+func TestPointerResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"vtolstov"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+	req := c.NewRequest("test", "/test", &Request{})
+
+	var nilrsp *Request
+	if err := c.Call(context.TODO(), req, &nilrsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if nilrsp == nil || nilrsp.Name != "vtolstov" {
+		t.Fatalf("invalid response %#+v", nilrsp)
+	}
+
+	rsp := &Request{Field1: "keep"}
+	if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "vtolstov" || rsp.Field1 != "keep" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+
+	if err := c.Call(context.TODO(), req, (*Request)(nil), client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected error for nil response pointer")
+	}
+}
+
+func TestAcceptFallback(t *testing.T) {
+	var accepts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepts = append(accepts, r.Header.Get("Accept"))
+		if r.Header.Get("Accept") != "application/vnd.api+json" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		// decoded with accepted type
+		w.Header()["Content-Type"] = nil
+		_, _ = w.Write([]byte(`{"name":"vtolstov"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		client.Codec("application/json", &failReadCodec{codec.NewCodec()}),
+		client.Codec("application/vnd.api+json", codec.NewCodec()),
+	)
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL),
+		WithAcceptFallback("text/xml", "application/vnd.api+json")); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "vtolstov" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+	if len(accepts) != 3 || accepts[1] != "text/xml" || accepts[2] != "application/vnd.api+json" {
+		t.Fatalf("invalid accept headers %v", accepts)
+	}
+}
+
+func TestRawBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q,"field1":%q}`, buf, r.Header.Get("Content-Type"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		client.Codec("application/octet-stream", codec.NewCodec()),
+	)
+
+	payload := `{"name":"vtolstov"}`
+	for _, tc := range []struct {
+		body interface{}
+		ct   string
+	}{
+		{[]byte(payload), "application/octet-stream"},
+		{json.RawMessage(payload), "application/json"},
+		{&codec.Frame{Data: []byte(payload)}, "application/octet-stream"},
+	} {
+		rsp := &Request{}
+		req := c.NewRequest("test", "/test", tc.body, client.RequestContentType("application/octet-stream"))
+		if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != payload || rsp.Field1 != tc.ct {
+			t.Fatalf("%T: invalid body %q or content type %q", tc.body, rsp.Name, rsp.Field1)
+		}
+	}
+}
+
+func TestConflictRetry(t *testing.T) {
+	var version int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			_, _ = fmt.Fprintf(w, `{"name":"resource","field3":%d}`, version)
+			return
+		}
+		req := &Request{}
+		_ = json.NewDecoder(r.Body).Decode(req)
+		if int(req.Field3) != version {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		version++
+		_, _ = fmt.Fprintf(w, `{"name":%q,"field3":%d}`, req.Name, version)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	// resource updated concurrently, so first request has stale version
+	version = 5
+	var rereads int
+	reread := func(ctx context.Context) (client.Request, error) {
+		rereads++
+		cur := &Request{}
+		if err := c.Call(ctx, c.NewRequest("test", "/test", nil), cur, client.WithAddress(ts.URL), Method(http.MethodGet)); err != nil {
+			return nil, err
+		}
+		return c.NewRequest("test", "/test", &Request{Name: "updated", Field3: cur.Field3}), nil
+	}
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "updated", Field3: 4}), rsp,
+		client.WithAddress(ts.URL), WithConflictRetry(reread, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if rereads != 1 || rsp.Name != "updated" || rsp.Field3 != 6 {
+		t.Fatalf("invalid result rereads %d rsp %#+v", rereads, rsp)
+	}
+
+	err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "updated", Field3: 1}), rsp,
+		client.WithAddress(ts.URL), WithConflictRetry(func(ctx context.Context) (client.Request, error) {
+			return c.NewRequest("test", "/test", &Request{Name: "updated", Field3: 1}), nil
+		}, 2))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusConflict {
+		t.Fatalf("expected conflict after retries, got %v", err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), Proxy(proxy.URL))
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress("http://service.invalid")); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "http://service.invalid/test" {
+		t.Fatalf("request not sent via proxy %#+v", rsp)
+	}
+}
+
+func TestDeadlineHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.Header.Get("X-Deadline"))
+	}))
+	defer ts.Close()
+
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.TODO(), deadline)
+	defer cancel()
+
+	for format, expect := range map[DeadlineFormat]string{
+		DeadlineRFC3339:    deadline.UTC().Format(time.RFC3339Nano),
+		DeadlineUnixMillis: fmt.Sprintf("%d", deadline.UnixNano()/int64(time.Millisecond)),
+	} {
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), DeadlineHeader("", format))
+		rsp := &Request{}
+		if err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != expect {
+			t.Fatalf("format %d: expected deadline %q, got %q", format, expect, rsp.Name)
+		}
+	}
+}
+
+func TestCallContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Content-Type"] = nil
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.Header.Get("Content-Type"))
+	}))
+	defer ts.Close()
+
+	// default codec can't decode, so response decoded by call codec
+	c := NewClient(
+		client.ContentType("application/json"),
+		client.Codec("application/json", &failReadCodec{codec.NewCodec()}),
+		client.Codec("application/x-test", codec.NewCodec()),
+	)
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL), client.WithContentType("application/x-test")); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "application/x-test" {
+		t.Fatalf("invalid request content type %q", rsp.Name)
+	}
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("default codec must be used without call content type")
+	}
+}
+
+type envelopeError struct {
+	Status int
+	Reason string
+}
+
+func (e *envelopeError) Error() string { return e.Reason }
+
+func TestErrorDecoder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte(`teapot`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	decoder := func(status int, header http.Header, body []byte) error {
+		return &envelopeError{Status: status, Reason: header.Get("Content-Type") + ":" + string(body)}
+	}
+
+	err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{},
+		client.WithAddress(ts.URL),
+		ErrorMap(map[string]interface{}{"418": &Error{}}),
+		WithErrorDecoder(decoder),
+	)
+	eerr, ok := err.(*envelopeError)
+	if !ok {
+		t.Fatalf("expected envelope error, got %T %v", err, err)
+	}
+	if eerr.Status != http.StatusTeapot || eerr.Reason != "text/plain:teapot" {
+		t.Fatalf("invalid error %#+v", eerr)
+	}
+}
+
+func TestNoRequestTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.Header.Get(metadata.HeaderTimeout))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.RequestTimeout(50*time.Millisecond))
+
+	req := c.NewRequest("test", "/test", &Request{})
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected timeout error")
+	}
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), WithNoRequestTimeout()); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "" {
+		t.Fatalf("timeout header must not be sent, got %q", rsp.Name)
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var buf bytes.Buffer
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &buf, client.WithAddress(ts.URL), WithStreamResponse()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("invalid response size %d", buf.Len())
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q,"field1":%q}`, strings.Join(r.TransferEncoding, ","), fmt.Sprintf("%d", r.ContentLength))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "test"}), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "" || rsp.Field1 == "-1" {
+		t.Fatalf("expected content length, got %#+v", rsp)
+	}
+
+	rsp = &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "test"}, WithContentLength(-1)), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "chunked" || rsp.Field1 != "-1" {
+		t.Fatalf("expected chunked request, got %#+v", rsp)
+	}
+}
+
+func TestWrapRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, strings.Join(r.Header.Values("X-Wrapper"), ","))
+	}))
+	defer ts.Close()
+
+	wrapper := func(name string) RoundTripperWrapper {
+		return func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				r.Header.Add("X-Wrapper", name)
+				return rt.RoundTrip(r)
+			})
+		}
+	}
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		WrapRoundTripper(wrapper("first")),
+		WrapRoundTripper(wrapper("second")),
+	)
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "first,second" {
+		t.Fatalf("invalid wrappers order %q", rsp.Name)
+	}
+}
+
+func TestPreserveHeaderCase(t *testing.T) {
+	var header http.Header
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})
+
+	for _, raw := range []bool{false, true} {
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), PreserveHeaderCase(raw))
+		ctx := metadata.NewOutgoingContext(context.TODO(), metadata.Metadata{"X-my-header": "value"})
+		if err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), &Request{},
+			client.WithAddress("http://127.0.0.1:1"), WithRoundTripper(rt)); err != nil {
+			t.Fatal(err)
+		}
+		_, rawOK := header["X-my-header"]
+		_, canonicalOK := header["X-My-Header"]
+		if rawOK != raw || canonicalOK == raw {
+			t.Fatalf("invalid header keys with raw %v: %v", raw, header)
+		}
+	}
+}
+
+func TestMultiValueMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-Values", "c")
+		w.Header().Add("X-Values", "d")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, strings.Join(r.Header.Values("X-Values"), "|"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	ctx := metadata.NewOutgoingContext(context.TODO(), metadata.Metadata{"X-Values": "a, b\nc"})
+	rsp := &Request{}
+	var md metadata.Metadata
+	if err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL), ResponseMetadata(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "a, b|c" {
+		t.Fatalf("invalid request header values %q", rsp.Name)
+	}
+	if v, _ := md.Get("X-Values"); v != "c, d" {
+		t.Fatalf("invalid response header values %q", v)
+	}
+}
+
+func TestInitCodec(t *testing.T) {
+	c := NewClient()
+	if err := c.Init(); err == nil || !strings.Contains(err.Error(), "application/json") {
+		t.Fatalf("expected missing codec error, got %v", err)
+	}
+
+	c = NewClient(client.Codec("application/json", codec.NewCodec()))
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestOverridesPrecedence(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.Method+" "+r.URL.Path+" "+string(buf))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+	msg := &Request{Name: "name", Field1: "field1"}
+
+	tests := []struct {
+		ropts  []client.RequestOption
+		copts  []client.CallOption
+		expect string
+	}{
+		{
+			ropts:  []client.RequestOption{WithMethod(http.MethodPut), WithPath("/request"), WithBody("name")},
+			expect: `PUT /request {"name":"name","field1":""`,
+		},
+		{
+			ropts:  []client.RequestOption{WithMethod(http.MethodPut), WithPath("/request"), WithBody("name")},
+			copts:  []client.CallOption{Method(http.MethodPatch), Path("/call"), Body("field1")},
+			expect: `PATCH /call {"name":"","field1":"field1"`,
+		},
+		{
+			ropts:  []client.RequestOption{WithMethod(http.MethodPut)},
+			copts:  []client.CallOption{Path("/call")},
+			expect: `PUT /call`,
+		},
+	}
+
+	for _, tt := range tests {
+		rsp := &Request{}
+		opts := append([]client.CallOption{client.WithAddress(ts.URL)}, tt.copts...)
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", msg, tt.ropts...), rsp, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(rsp.Name, tt.expect) {
+			t.Fatalf("expected %q, got %q", tt.expect, rsp.Name)
+		}
+	}
+}
+
+func TestNoBodyMethods(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Body", fmt.Sprintf("%d|%v|%s|%s", r.ContentLength, r.Header.Values("Content-Length"), buf, r.URL.RawQuery))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	for method, expect := range map[string]string{
+		http.MethodGet:    "0|[]||name=name",
+		http.MethodHead:   "0|[]||name=name",
+		http.MethodDelete: `64|[64]|{"name":"name","field1":"","ClientID":"","Field2":"","Field3":0}|`,
+	} {
+		var md metadata.Metadata
+		req := c.NewRequest("test", "/test", &Request{Name: "name"}, WithMethod(method))
+		if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL), ResponseMetadata(&md)); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := md.Get("X-Body"); v != expect {
+			t.Fatalf("%s: expected body %q, got %q", method, expect, v)
+		}
+	}
+}
+
+func TestPartialResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/truncated" {
+			// connection closed before declared length sent
+			w.Header().Set("Content-Length", "100")
+		}
+		_, _ = w.Write([]byte(`{"name":"vtol`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	for path, code := range map[string]int32{"/truncated": http.StatusBadGateway, "/malformed": http.StatusInternalServerError} {
+		err := c.Call(context.TODO(), c.NewRequest("test", path, &Request{}), &Request{}, client.WithAddress(ts.URL))
+		if verr := errors.FromError(err); err == nil || verr.Code != code {
+			t.Fatalf("%s: expected error with code %d, got %v", path, code, err)
+		}
+	}
+
+	for rerr, code := range map[error]int32{
+		io.ErrUnexpectedEOF: http.StatusBadGateway,
+		&net.OpError{Op: "read", Err: fmt.Errorf("connection reset")}: http.StatusBadGateway,
+		context.Canceled:             http.StatusRequestTimeout,
+		context.DeadlineExceeded:     http.StatusRequestTimeout,
+		fmt.Errorf("gzip: bad data"): http.StatusInternalServerError,
+	} {
+		body := &trackedBody{err: rerr}
+		if verr := errors.FromError(body.error(rerr)); verr.Code != code {
+			t.Fatalf("%v: expected error with code %d, got %v", rerr, code, verr)
+		}
+	}
+}
+
+func TestMetadataFilter(t *testing.T) {
+	var header http.Header
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})
+
+	md := metadata.Metadata{"X-Tenant-Id": "1", "X-Locale": "en", "X-Internal-Route": "a"}
+	tests := []struct {
+		opt    client.Option
+		expect []string
+	}{
+		{MetadataFilter(nil, nil), []string{"X-Tenant-Id", "X-Locale", "X-Internal-Route"}},
+		{MetadataFilter([]string{"x-tenant-id", "x-locale"}, nil), []string{"X-Tenant-Id", "X-Locale"}},
+		{MetadataFilter(nil, []string{"x-internal-route"}), []string{"X-Tenant-Id", "X-Locale"}},
+		{MetadataFilter([]string{"x-tenant-id", "x-locale"}, []string{"x-locale"}), []string{"X-Tenant-Id"}},
+	}
+
+	for _, tt := range tests {
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), tt.opt)
+		if err := c.Call(metadata.NewOutgoingContext(context.TODO(), md), c.NewRequest("test", "/test", &Request{}), &Request{},
+			client.WithAddress("http://127.0.0.1:1"), WithRoundTripper(rt)); err != nil {
+			t.Fatal(err)
+		}
+		var sent []string
+		for k := range md {
+			if header.Get(k) != "" {
+				sent = append(sent, k)
+			}
+		}
+		if len(sent) != len(tt.expect) {
+			t.Fatalf("expected headers %v, got %v", tt.expect, sent)
+		}
+		for _, k := range tt.expect {
+			if header.Get(k) == "" {
+				t.Fatalf("expected headers %v, got %v", tt.expect, sent)
+			}
+		}
+	}
+}
+
+func TestRetryIdempotentOnly(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), RetryIdempotentOnly(true),
+		client.Retries(2), client.Retry(client.RetryAlways))
+
+	tests := []struct {
+		ropts  []client.RequestOption
+		expect int32
+	}{
+		{nil, 1},
+		{[]client.RequestOption{WithMethod(http.MethodPut)}, 3},
+		{[]client.RequestOption{WithIdempotent(true)}, 3},
+		{[]client.RequestOption{WithMethod(http.MethodGet), WithIdempotent(false)}, 1},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&calls, 0)
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}, tt.ropts...), &Request{}, client.WithAddress(ts.URL)); err == nil {
+			t.Fatal("expected error")
+		}
+		if n := atomic.LoadInt32(&calls); n != tt.expect {
+			t.Fatalf("expected %d calls, got %d", tt.expect, n)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var hreq http.Request
+	ctx := metadata.NewOutgoingContext(context.TODO(), metadata.Metadata{"X-Test": "test"})
+	if err := c.Call(ctx, c.NewRequest("test", "/v1/test/{name}", &Request{Name: "dry", Field1: "run"}), &Request{},
+		client.WithAddress("http://127.0.0.1:1"), WithDryRun(&hreq)); err != nil {
+		t.Fatal(err)
+	}
+
+	if hreq.Method != http.MethodPost || hreq.URL.String() != "http://127.0.0.1:1/v1/test/dry" || hreq.Header.Get("X-Test") != "test" {
+		t.Fatalf("invalid request %s %s %v", hreq.Method, hreq.URL, hreq.Header)
+	}
+	buf, err := io.ReadAll(hreq.Body)
+	_ = hreq.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(buf), `{"name":"","field1":"run"`) {
+		t.Fatalf("invalid body %s", buf)
+	}
+}
+
+func TestHeadRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total", "10")
+		if r.URL.Path == "/empty" {
+			w.Header().Set("Content-Length", "0")
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"body"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var md metadata.Metadata
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}, WithMethod(http.MethodHead)), rsp,
+		client.WithAddress(ts.URL), ResponseMetadata(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := md.Get("X-Total"); v != "10" || rsp.Name != "" {
+		t.Fatalf("invalid head response %v %#+v", md, rsp)
+	}
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/empty", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q}`, r.URL.RequestURI()+" "+string(buf))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	req := c.NewRequest("test", "/v1/{name}", &Request{Name: "abs"})
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress("http://127.0.0.1:1"), WithURL(ts.URL+"/external/path?x=1")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rsp.Name, `/external/path?x=1 {"name":"abs"`) {
+		t.Fatalf("invalid request %q", rsp.Name)
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`not json`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	// the same error with and without error map if body not matched error type
+	for _, opts := range [][]client.CallOption{nil, {ErrorMap(map[string]interface{}{"404": &Request{}})}} {
+		err := c.Call(context.TODO(), c.NewRequest("users", "/test", &Request{}), &Request{}, append(opts, client.WithAddress(ts.URL))...)
+		verr, ok := err.(*errors.Error)
+		if !ok {
+			t.Fatalf("expected micro error, got %T %v", err, err)
+		}
+		if verr.Id != "users" || verr.Code != http.StatusNotFound || verr.Detail != "not json" {
+			t.Fatalf("invalid error %#+v", verr)
+		}
+	}
+}
+
+func TestCallRetriesOverride(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	var backoffs []int
+	c := NewClient(client.Codec("application/json", codec.NewCodec()),
+		client.Retries(2), client.Retry(client.RetryAlways))
+
+	tests := []struct {
+		opts   []client.CallOption
+		expect int32
+	}{
+		{nil, 3},
+		{[]client.CallOption{client.WithRetries(0)}, 1},
+		{[]client.CallOption{client.WithRetries(4), client.WithBackoff(func(_ context.Context, _ client.Request, i int) (time.Duration, error) {
+			backoffs = append(backoffs, i)
+			return 0, nil
+		})}, 5},
+		{nil, 3},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&calls, 0)
+		opts := append([]client.CallOption{client.WithAddress(ts.URL)}, tt.opts...)
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, opts...); err == nil {
+			t.Fatal("expected error")
+		}
+		if n := atomic.LoadInt32(&calls); n != tt.expect {
+			t.Fatalf("expected %d calls, got %d", tt.expect, n)
+		}
+	}
+
+	if fmt.Sprint(backoffs) != "[0 1 2 3 4]" {
+		t.Fatalf("invalid backoff attempts %v", backoffs)
+	}
+}
+
+func TestCallValue(t *testing.T) {
+	type accountKey struct{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Account") != "acc1" || r.Header.Get("X-Wrapper-Account") != "acc1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	wrapper := func(next client.CallFunc) client.CallFunc {
+		return func(ctx context.Context, addr string, req client.Request, rsp interface{}, opts client.CallOptions) error {
+			if acc, ok := opts.Context.Value(accountKey{}).(string); ok {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				md = metadata.Copy(md)
+				md.Set("X-Wrapper-Account", acc)
+				ctx = metadata.NewOutgoingContext(ctx, md)
+			}
+			return next(ctx, addr, req, rsp, opts)
+		}
+	}
+	rt := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(hreq *http.Request) (*http.Response, error) {
+			if acc, ok := hreq.Context().Value(accountKey{}).(string); ok {
+				hreq.Header.Set("X-Account", acc)
+			}
+			return next.RoundTrip(hreq)
+		})
+	}
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.WrapCall(wrapper), WrapRoundTripper(rt))
+
+	req := c.NewRequest("test", "/test", &Request{})
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL), WithValue(accountKey{}, "acc1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected error without value")
+	}
+}
+
+func TestCallSingleAttemptTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 408 {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+
+	// backoff and wrapper ignoring context not delay call return
+	block := make(chan struct{})
+	defer close(block)
+	for _, opt := range []client.Option{
+		client.Backoff(func(context.Context, client.Request, int) (time.Duration, error) { return time.Second, nil }),
+		client.WrapCall(func(fn client.CallFunc) client.CallFunc {
+			return func(ctx context.Context, addr string, req client.Request, rsp interface{}, opts client.CallOptions) error {
+				<-block
+				return nil
+			}
+		}),
+	} {
+		c = NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(0), opt)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		err = c.Call(ctx, c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL))
+		cancel()
+		if verr, ok := err.(*errors.Error); !ok || verr.Code != 408 || time.Since(start) > 500*time.Millisecond {
+			t.Fatalf("expected timeout error on context done, got %v after %v", err, time.Since(start))
+		}
+	}
+}
+
+func TestAttempts(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// first two attempts fail
+		if atomic.AddInt32(&calls, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(3), client.Retry(client.RetryAlways))
+
+	var attempts int
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL), WithAttempts(&attempts)); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL), client.WithRetries(0), WithAttempts(&attempts)); err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestOptionsRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions || r.ContentLength > 0 || r.URL.Query().Get("name") != "test" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Add("Allow", "GET, HEAD")
+		w.Header().Add("Allow", "OPTIONS")
+		_, _ = w.Write([]byte("GET, HEAD, OPTIONS"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	var methods []string
+	var md metadata.Metadata
+	req := c.NewRequest("test", "/test", &Request{Name: "test"}, WithMethod(http.MethodOptions))
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL), WithAllowedMethods(&methods), ResponseMetadata(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(methods) != "[GET HEAD OPTIONS]" {
+		t.Fatalf("invalid allowed methods %v", methods)
+	}
+	if v, _ := md.Get("Allow"); v != "GET, HEAD, OPTIONS" {
+		t.Fatalf("invalid response metadata %v", md)
+	}
+}
+
+func TestHeaderMapper(t *testing.T) {
+	var header http.Header
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})
+
+	mapper := func(k string) (string, bool) {
+		if strings.EqualFold(k, "Secret") {
+			return "", false
+		}
+		return "X-Micro-" + k, true
+	}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), HeaderMapper(mapper), MetadataFilter(nil, []string{"denied"}))
+
+	md := metadata.Metadata{"Tenant": "1", "Secret": "s", "Denied": "d"}
+	if err := c.Call(metadata.NewOutgoingContext(context.TODO(), md), c.NewRequest("test", "/test", &Request{}), &Request{},
+		client.WithAddress("http://127.0.0.1:1"), WithRoundTripper(rt), Metadata(metadata.Metadata{"Region": "eu"})); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Micro-Tenant") != "1" || header.Get("X-Micro-Region") != "eu" {
+		t.Fatalf("mapped headers not sent %v", header)
+	}
+	for _, k := range []string{"Tenant", "Secret", "X-Micro-Secret", "X-Micro-Denied"} {
+		if header.Get(k) != "" {
+			t.Fatalf("header %s must not be sent %v", k, header)
+		}
+	}
+}
+
+func TestEnvelopeStatus(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) == 1 || r.URL.Path == "/missing" {
+			_, _ = w.Write([]byte(`{"code":503,"name":"busy"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"name":"ok"}`))
+	}))
+	defer ts.Close()
+
+	envelope := func(body []byte) (int, error) {
+		var v struct {
+			Code int `json:"code"`
+		}
+		err := json.Unmarshal(body, &v)
+		return v.Code, err
+	}
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(1), client.Retry(client.RetryAlways))
+
+	// envelope error retried like http error
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL), WithEnvelopeStatus(envelope)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "ok" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("invalid response %#+v after %d calls", rsp, calls)
+	}
+
+	err := c.Call(context.TODO(), c.NewRequest("test", "/missing", &Request{}), rsp, client.WithAddress(ts.URL), WithEnvelopeStatus(envelope), client.WithRetries(0))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 503 || verr.Id != "test" || verr.Detail != `{"code":503,"name":"busy"}` {
+		t.Fatalf("expected envelope error, got %v", err)
+	}
+
+	// envelope body buffered up to max receive size
+	c = NewClient(client.Codec("application/json", codec.NewCodec()), MaxRecvMsgSize(8))
+	err = c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL), WithEnvelopeStatus(envelope))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusBadGateway {
+		t.Fatalf("expected envelope size error, got %v", err)
+	}
+}
+
+func TestPublishWebhookConfirm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events/accepted":
+			w.WriteHeader(http.StatusAccepted)
+		case "/events/redirect":
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"id":"events","detail":"invalid event","code":422}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()),
+		PublishWebhook(func(topic string) string { return ts.URL + "/events/" + topic }))
+
+	if err := c.Publish(context.TODO(), c.NewMessage("accepted", &Request{})); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.Publish(context.TODO(), c.NewMessage("redirect", &Request{}))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != http.StatusNotModified || verr.Id != "redirect" {
+		t.Fatalf("expected not confirmed delivery error, got %v", err)
+	}
+
+	err = c.Publish(context.TODO(), c.NewMessage("invalid", &Request{}),
+		WithPublishErrorMap(map[string]interface{}{"422": &errors.Error{}}))
+	if verr, ok := err.(*errors.Error); !ok || verr.Detail != "invalid event" || verr.Id != "events" {
+		t.Fatalf("expected mapped error, got %v", err)
+	}
+}
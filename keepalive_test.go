@@ -0,0 +1,81 @@
+//go:build !windows
+// +build !windows
+
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+)
+
+func keepAliveEnabled(t *testing.T, conn net.Conn) bool {
+	rc, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if err = rc.Control(func(fd uintptr) {
+		v, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return v != 0
+}
+
+func TestKeepAlive(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	dialer := &net.Dialer{}
+	custom := func(o *client.Options) {
+		o.ContextDialer = func(ctx context.Context, addr string) (net.Conn, error) {
+			return net.Dial("tcp", addr)
+		}
+	}
+
+	tests := []struct {
+		opts   []client.Option
+		expect bool
+	}{
+		{nil, true},
+		{[]client.Option{KeepAlive(-1)}, false},
+		{[]client.Option{KeepAlive(time.Minute)}, true},
+		{[]client.Option{HTTPDialer(dialer), KeepAlive(-1)}, false},
+		{[]client.Option{custom, KeepAlive(-1)}, false},
+		{[]client.Option{custom, KeepAlive(time.Minute)}, true},
+	}
+
+	for i, tt := range tests {
+		c := NewClient(tt.opts...).(*httpClient)
+		conn, err := c.httpcli.Transport.(*http.Transport).DialContext(context.TODO(), "tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := keepAliveEnabled(t, conn); v != tt.expect {
+			t.Fatalf("test %d: expected keep-alive %v, got %v", i, tt.expect, v)
+		}
+		conn.Close()
+	}
+
+	if dialer.KeepAlive != 0 {
+		t.Fatal("caller dialer must not be modified")
+	}
+}
@@ -0,0 +1,19 @@
+package http
+
+import (
+	"time"
+)
+
+// LatencyRecorder can be implemented by selector to receive round-trip time
+// of each call, for example to prefer faster nodes
+type LatencyRecorder interface {
+	RecordLatency(node string, latency time.Duration, err error) error
+}
+
+// record passes call result to selector, latency reported if selector supports it
+func (h *httpClient) record(node string, latency time.Duration, err error) error {
+	if lr, ok := h.opts.Selector.(LatencyRecorder); ok {
+		return lr.RecordLatency(node, latency, err)
+	}
+	return h.opts.Selector.Record(node, err)
+}
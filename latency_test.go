@@ -0,0 +1,46 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+type latencySelector struct {
+	roundRobinSelector
+	latency map[string]time.Duration
+	mu      sync.Mutex
+}
+
+func (s *latencySelector) RecordLatency(node string, latency time.Duration, err error) error {
+	s.mu.Lock()
+	s.latency[node] = latency
+	s.mu.Unlock()
+	return nil
+}
+
+func TestRecordLatency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	s := &latencySelector{latency: make(map[string]time.Duration)}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(s))
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := s.latency[ts.URL]; d < 50*time.Millisecond {
+		t.Fatalf("invalid latency recorded %v", d)
+	}
+}
@@ -0,0 +1,427 @@
+package http
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/errors"
+)
+
+// hostLimiter limits number of concurrent requests per host,
+// host removed when it has no active or waiting requests so map does not grow unbounded
+type hostLimiter struct {
+	hosts map[string]*hostSemaphore
+	max   int
+	sync.Mutex
+}
+
+// hostSemaphore counts holders and waiters of host semaphore
+type hostSemaphore struct {
+	*prioritySemaphore
+	refs int
+}
+
+func newHostLimiter(max int) *hostLimiter {
+	return &hostLimiter{hosts: make(map[string]*hostSemaphore), max: max}
+}
+
+// ref returns semaphore of host and takes reference to it
+func (l *hostLimiter) ref(host string) *prioritySemaphore {
+	l.Lock()
+	defer l.Unlock()
+	s, ok := l.hosts[host]
+	if !ok {
+		s = &hostSemaphore{prioritySemaphore: &prioritySemaphore{max: l.max}}
+		l.hosts[host] = s
+	}
+	s.refs++
+	return s.prioritySemaphore
+}
+
+// unref drops reference to semaphore of host, removes unused one
+func (l *hostLimiter) unref(host string) {
+	l.Lock()
+	defer l.Unlock()
+	if s, ok := l.hosts[host]; ok {
+		if s.refs--; s.refs <= 0 {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+// Acquire blocks until request to host can be done, waiters with higher priority dequeued first
+func (l *hostLimiter) Acquire(ctx context.Context, host string, priority int) error {
+	if err := l.ref(host).Acquire(ctx, priority); err != nil {
+		l.unref(host)
+		return err
+	}
+	return nil
+}
+
+// Release frees slot acquired by Acquire
+func (l *hostLimiter) Release(host string) {
+	l.Lock()
+	s, ok := l.hosts[host]
+	l.Unlock()
+	if !ok {
+		return
+	}
+	s.Release()
+	l.unref(host)
+}
+
+// Waiters returns number of requests to host waiting for slot
+func (l *hostLimiter) Waiters(host string) int {
+	l.Lock()
+	s, ok := l.hosts[host]
+	l.Unlock()
+	if !ok {
+		return 0
+	}
+	return s.Waiters()
+}
+
+type waiter struct {
+	ch       chan struct{}
+	priority int
+	seq      uint64
+	index    int
+}
+
+// waitQueue implements heap.Interface, higher priority first, fifo for equal priority
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority == q[j].priority {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].priority > q[j].priority
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
+
+type prioritySemaphore struct {
+	waiters waitQueue
+	seq     uint64
+	active  int
+	max     int
+	sync.Mutex
+}
+
+func (s *prioritySemaphore) Acquire(ctx context.Context, priority int) error {
+	s.Lock()
+	if s.active < s.max && len(s.waiters) == 0 {
+		s.active++
+		s.Unlock()
+		return nil
+	}
+	s.seq++
+	w := &waiter{ch: make(chan struct{}), priority: priority, seq: s.seq}
+	heap.Push(&s.waiters, w)
+	s.Unlock()
+
+	select {
+	case <-w.ch:
+		return nil
+	case <-ctx.Done():
+		s.Lock()
+		if w.index >= 0 {
+			heap.Remove(&s.waiters, w.index)
+			s.Unlock()
+			return ctx.Err()
+		}
+		s.Unlock()
+		// slot already handed to us, pass it to the next waiter
+		s.Release()
+		return ctx.Err()
+	}
+}
+
+func (s *prioritySemaphore) Release() {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.waiters) > 0 {
+		w := heap.Pop(&s.waiters).(*waiter)
+		close(w.ch)
+		return
+	}
+	s.active--
+}
+
+// Waiters returns number of requests waiting for slot
+func (s *prioritySemaphore) Waiters() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.waiters)
+}
+
+type budgetWaiter struct {
+	ch chan struct{}
+	n  int64
+}
+
+// byteBudget limits total size of request bodies held in memory by in-flight requests
+type byteBudget struct {
+	waiters []*budgetWaiter
+	used    int64
+	size    int64
+	sync.Mutex
+}
+
+func newByteBudget(size int64) *byteBudget {
+	return &byteBudget{size: size}
+}
+
+// Acquire blocks until n bytes available, n bigger than budget size treated as whole budget,
+// returns acquired bytes that must be passed to Release
+func (b *byteBudget) Acquire(ctx context.Context, n int64) (int64, error) {
+	if n > b.size {
+		n = b.size
+	}
+	b.Lock()
+	if b.size-b.used >= n && len(b.waiters) == 0 {
+		b.used += n
+		b.Unlock()
+		return n, nil
+	}
+	w := &budgetWaiter{ch: make(chan struct{}), n: n}
+	b.waiters = append(b.waiters, w)
+	b.Unlock()
+
+	select {
+	case <-w.ch:
+		return n, nil
+	case <-ctx.Done():
+		b.Lock()
+		for i, v := range b.waiters {
+			if v == w {
+				b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+				b.notify()
+				b.Unlock()
+				return 0, ctx.Err()
+			}
+		}
+		b.Unlock()
+		// bytes already handed to us, pass it to the next waiters
+		b.Release(n)
+		return 0, ctx.Err()
+	}
+}
+
+// Release frees bytes acquired by Acquire
+func (b *byteBudget) Release(n int64) {
+	b.Lock()
+	b.used -= n
+	b.notify()
+	b.Unlock()
+}
+
+// notify wakes waiters in fifo order while budget allows, must be called under lock
+func (b *byteBudget) notify() {
+	for len(b.waiters) > 0 {
+		w := b.waiters[0]
+		if b.size-b.used < w.n {
+			return
+		}
+		b.used += w.n
+		b.waiters = b.waiters[1:]
+		close(w.ch)
+	}
+}
+
+// adaptiveLimiter limits number of in-flight requests with limit adjusted by AIMD,
+// overload signals like 503 or timeout halve the limit, successful responses increase it
+// by one per limit requests, so struggling backend gets less load than retry storm gives.
+// Limit halved at most once per window: overload of request started before last decrease
+// already accounted, so burst of in-flight requests failing together does not drop it to min
+type adaptiveLimiter struct {
+	waiters []chan struct{}
+	limit   float64
+	min     float64
+	max     float64
+	active  int
+	gen     uint64
+	sync.Mutex
+}
+
+func newAdaptiveLimiter(min, max int) *adaptiveLimiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &adaptiveLimiter{limit: float64(max), min: float64(min), max: float64(max)}
+}
+
+// Acquire blocks until number of in-flight requests is below current limit,
+// returns limit generation that must be passed to Release
+func (l *adaptiveLimiter) Acquire(ctx context.Context) (uint64, error) {
+	l.Lock()
+	if l.active < int(l.limit) && len(l.waiters) == 0 {
+		l.active++
+		gen := l.gen
+		l.Unlock()
+		return gen, nil
+	}
+	ch := make(chan struct{})
+	l.waiters = append(l.waiters, ch)
+	l.Unlock()
+
+	select {
+	case <-ch:
+		l.Lock()
+		gen := l.gen
+		l.Unlock()
+		return gen, nil
+	case <-ctx.Done():
+		l.Lock()
+		for i, w := range l.waiters {
+			if w == ch {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				l.Unlock()
+				return 0, ctx.Err()
+			}
+		}
+		l.Unlock()
+		// slot already handed to us, pass it to the next waiter
+		l.Release(0, loadNone)
+		return 0, ctx.Err()
+	}
+}
+
+// Release frees slot and adjusts limit by backend load signal of request,
+// gen is generation returned by Acquire
+func (l *adaptiveLimiter) Release(gen uint64, load loadSignal) {
+	l.Lock()
+	defer l.Unlock()
+	l.active--
+	switch load {
+	case loadOverload:
+		// request started before last decrease, its overload already accounted
+		if gen != l.gen {
+			break
+		}
+		l.gen++
+		l.limit /= 2
+		if l.limit < l.min {
+			l.limit = l.min
+		}
+	case loadOK:
+		l.limit += 1 / l.limit
+		if l.limit > l.max {
+			l.limit = l.max
+		}
+	}
+	for len(l.waiters) > 0 && l.active < int(l.limit) {
+		l.active++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
+
+// Limit returns current limit of in-flight requests
+func (l *adaptiveLimiter) Limit() int {
+	l.Lock()
+	defer l.Unlock()
+	return int(l.limit)
+}
+
+// loadSignal is backend load observed by request
+type loadSignal int
+
+const (
+	// loadNone request gives no signal, like canceled request or client side error
+	loadNone loadSignal = iota
+	// loadOK backend served request
+	loadOK
+	// loadOverload backend sheds load or does not respond in time
+	loadOverload
+)
+
+// loadOf returns load signal of backend response status
+func loadOf(status int) loadSignal {
+	switch status {
+	case http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusGatewayTimeout:
+		return loadOverload
+	}
+	return loadOK
+}
+
+// RateLimiter limits rate of requests, Wait blocks until request allowed
+// or returns error if it can't be allowed, *rate.Limiter from golang.org/x/time/rate implements it
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// keyedRateLimiter holds rate limiter per request key
+type keyedRateLimiter struct {
+	key        func(req client.Request) string
+	newLimiter func(key string) RateLimiter
+	limiters   map[string]RateLimiter
+	sync.Mutex
+}
+
+func newKeyedRateLimiter(key func(req client.Request) string, newLimiter func(key string) RateLimiter) *keyedRateLimiter {
+	return &keyedRateLimiter{key: key, newLimiter: newLimiter, limiters: make(map[string]RateLimiter)}
+}
+
+func (l *keyedRateLimiter) get(req client.Request) RateLimiter {
+	key := l.key(req)
+	l.Lock()
+	defer l.Unlock()
+	rl, ok := l.limiters[key]
+	if !ok {
+		rl = l.newLimiter(key)
+		l.limiters[key] = rl
+	}
+	return rl
+}
+
+// waitRate waits for global and keyed rate limiters
+func (h *httpClient) waitRate(ctx context.Context, req client.Request) error {
+	limiters := make([]RateLimiter, 0, 2)
+	if rl, ok := h.opts.Context.Value(rateLimiterKey{}).(RateLimiter); ok && rl != nil {
+		limiters = append(limiters, rl)
+	}
+	if h.keyedRate != nil {
+		if rl := h.keyedRate.get(req); rl != nil {
+			limiters = append(limiters, rl)
+		}
+	}
+	for _, rl := range limiters {
+		if err := rl.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
+			}
+			return errors.New("go.micro.client", fmt.Sprintf("rate limit exceeded: %v", err), http.StatusTooManyRequests)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+func TestPriorityLimiter(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	block := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/first" {
+			<-block
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithMaxConcurrentPerHost(1))
+	hc := c.(*httpClient)
+
+	call := func(wg *sync.WaitGroup, path string, priority int) {
+		defer wg.Done()
+		rsp := make(map[string]interface{})
+		req := c.NewRequest("test", path, &Request{Name: "vtolstov"})
+		if err := c.Call(context.TODO(), req, &rsp, client.WithAddress(ts.URL), WithPriority(priority)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	waitQueued := func(n int) {
+		for i := 0; i < 100; i++ {
+			if hc.limiter.Waiters(ts.Listener.Addr().String()) == n {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("requests not queued")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go call(&wg, "/first", 0)
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	go call(&wg, "/low1", 0)
+	waitQueued(1)
+	go call(&wg, "/low2", 0)
+	waitQueued(2)
+	go call(&wg, "/high", 10)
+	waitQueued(3)
+	close(block)
+	wg.Wait()
+
+	if len(order) != 4 || order[1] != "/high" || order[2] != "/low1" || order[3] != "/low2" {
+		t.Fatalf("invalid order %v", order)
+	}
+	if n := len(hc.limiter.hosts); n != 0 {
+		t.Fatalf("expected idle host removed, got %d hosts", n)
+	}
+}
+
+func TestByteBudget(t *testing.T) {
+	b := newByteBudget(100)
+
+	n, err := b.Acquire(context.TODO(), 80)
+	if err != nil || n != 80 {
+		t.Fatalf("acquire failed %d %v", n, err)
+	}
+
+	// budget exceeded, request waits
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = b.Acquire(ctx, 30); err == nil {
+		t.Fatal("budget must limit buffered bytes")
+	}
+
+	acquired := make(chan int64)
+	go func() {
+		n, _ := b.Acquire(context.TODO(), 500)
+		acquired <- n
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired before release")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.Release(80)
+	select {
+	case n = <-acquired:
+		if n != 100 {
+			t.Fatalf("oversized body must acquire whole budget, got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("not acquired after release")
+	}
+	b.Release(n)
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithBodyBufferBudget(16))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+	for i := 0; i < 3; i++ {
+		rsp := &Request{}
+		if err = c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "vtolstov"}), rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b = c.(*httpClient).budget
+	b.Lock()
+	defer b.Unlock()
+	if b.used != 0 {
+		t.Fatalf("budget not released, used %d", b.used)
+	}
+}
+
+func TestByteBudgetConcurrentBodies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	// each body takes more than half of budget, so calls wait for each other but both complete
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithBodyBufferBudget(1024))
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+			req := c.NewRequest("test", "/test", &Request{Name: strings.Repeat("x", 600)})
+			errs <- c.Call(ctx, req, &Request{}, client.WithAddress(ts.URL))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	var overload int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&overload) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), AdaptiveConcurrency(1, 8))
+	hc := c.(*httpClient)
+
+	call := func() error {
+		return c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL))
+	}
+
+	atomic.StoreInt32(&overload, 1)
+	for i := 0; i < 5; i++ {
+		_ = call()
+	}
+	if n := hc.adaptive.Limit(); n != 1 {
+		t.Fatalf("expected limit 1 after overload, got %d", n)
+	}
+
+	// only one request in flight, second waits for it
+	gen, err := hc.adaptive.Acquire(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := hc.adaptive.Acquire(ctx); err == nil {
+		t.Fatal("expected acquire to wait for slot")
+	}
+	hc.adaptive.Release(gen, loadNone)
+
+	atomic.StoreInt32(&overload, 0)
+	for i := 0; i < 10; i++ {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := hc.adaptive.Limit(); n <= 1 || n >= 8 {
+		t.Fatalf("expected limit slowly increased, got %d", n)
+	}
+
+	// timeout of client own host limiter is not backend overload
+	c = NewClient(client.Codec("application/json", codec.NewCodec()), AdaptiveConcurrency(1, 8), WithMaxConcurrentPerHost(1))
+	hc = c.(*httpClient)
+	host := strings.TrimPrefix(ts.URL, "http://")
+	if err := hc.limiter.Acquire(context.TODO(), host, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+		err := c.Call(ctx, c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL))
+		cancel()
+		if verr, ok := err.(*errors.Error); !ok || verr.Code != 408 {
+			t.Fatalf("expected limiter timeout, got %v", err)
+		}
+	}
+	hc.limiter.Release(host)
+	if len(hc.limiter.hosts) != 0 {
+		t.Fatalf("expected idle host removed, got %d hosts", len(hc.limiter.hosts))
+	}
+	if n := hc.adaptive.Limit(); n != 8 {
+		t.Fatalf("client side timeout must not lower limit, got %d", n)
+	}
+}
+
+func TestAdaptiveLimiterDecreaseWindow(t *testing.T) {
+	l := newAdaptiveLimiter(1, 16)
+
+	// burst of in-flight requests overloaded together halves limit once
+	gens := make([]uint64, 8)
+	for i := range gens {
+		gen, err := l.Acquire(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		gens[i] = gen
+	}
+	for _, gen := range gens {
+		l.Release(gen, loadOverload)
+	}
+	if n := l.Limit(); n != 8 {
+		t.Fatalf("expected single decrease to 8, got %d", n)
+	}
+
+	// request started after decrease lowers limit again
+	gen, err := l.Acquire(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Release(gen, loadOverload)
+	if n := l.Limit(); n != 4 {
+		t.Fatalf("expected decrease to 4, got %d", n)
+	}
+}
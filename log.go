@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"go.unistack.org/micro/v3/logger"
+)
+
+// snippetBody keeps first limit bytes read from response body
+type snippetBody struct {
+	io.ReadCloser
+	buf   []byte
+	limit int
+}
+
+func (b *snippetBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if left := b.limit - len(b.buf); left > 0 && n > 0 {
+		if left > n {
+			left = n
+		}
+		b.buf = append(b.buf, p[:left]...)
+	}
+	return n, err
+}
+
+func logCall(ctx context.Context, l logger.Logger, hreq *http.Request, hrsp *http.Response, latency time.Duration, body *snippetBody, err error) {
+	var status int
+	if hrsp != nil {
+		status = hrsp.StatusCode
+	}
+
+	if err == nil {
+		if l.V(logger.DebugLevel) {
+			l.Debugf(ctx, "%s %s %d %s", hreq.Method, hreq.URL.String(), status, latency)
+		}
+		return
+	}
+
+	if !l.V(logger.ErrorLevel) {
+		return
+	}
+	if body != nil && len(body.buf) > 0 {
+		l.Errorf(ctx, "%s %s %d %s failed: %v, body: %s", hreq.Method, hreq.URL.String(), status, latency, err, body.buf)
+		return
+	}
+	l.Errorf(ctx, "%s %s %d %s failed: %v", hreq.Method, hreq.URL.String(), status, latency, err)
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/logger"
+)
+
+type testLogger struct {
+	logger.Logger
+	sync.Mutex
+	debug []string
+	error []string
+}
+
+func (l *testLogger) V(level logger.Level) bool {
+	return true
+}
+
+func (l *testLogger) Debugf(ctx context.Context, msg string, args ...interface{}) {
+	l.Lock()
+	l.debug = append(l.debug, fmt.Sprintf(msg, args...))
+	l.Unlock()
+}
+
+func (l *testLogger) Errorf(ctx context.Context, msg string, args ...interface{}) {
+	l.Lock()
+	l.error = append(l.error, fmt.Sprintf(msg, args...))
+	l.Unlock()
+}
+
+func TestRequestLogger(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`upstream is down`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	for _, logBody := range []bool{false, true} {
+		l := &testLogger{Logger: logger.DefaultLogger}
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), RequestLogger(l), LogBody(logBody))
+
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/fail", &Request{}), rsp, client.WithAddress(ts.URL), client.WithRetries(0)); err == nil {
+			t.Fatal("expected error")
+		}
+
+		if len(l.debug) != 1 || !strings.HasPrefix(l.debug[0], "POST "+ts.URL+"/test 200 ") {
+			t.Fatalf("invalid debug log %v", l.debug)
+		}
+		if len(l.error) != 1 || !strings.HasPrefix(l.error[0], "POST "+ts.URL+"/fail 502 ") {
+			t.Fatalf("invalid error log %v", l.error)
+		}
+		if strings.Contains(l.error[0], "body: upstream is down") != logBody {
+			t.Fatalf("invalid body logging %v: %v", logBody, l.error)
+		}
+	}
+}
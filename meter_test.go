@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/meter"
+)
+
+type testCounter struct {
+	meter.Counter
+	m   *testMeter
+	key string
+}
+
+func (c *testCounter) Inc() {
+	c.m.Lock()
+	c.m.counters[c.key]++
+	c.m.Unlock()
+}
+
+type testMeter struct {
+	meter.Meter
+	counters map[string]int
+	sync.Mutex
+}
+
+func (m *testMeter) Counter(name string, labels ...string) meter.Counter {
+	return &testCounter{m: m, key: name + "{" + strings.Join(labels, ",") + "}"}
+}
+
+func TestRetryExhaustionCounter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	m := &testMeter{Meter: meter.NewMeter(), counters: make(map[string]int)}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Meter(m), WithRetryExhaustionCounter("retries_exhausted"))
+
+	opts := []client.CallOption{client.WithAddress(ts.URL), client.WithRetries(2), client.WithRetry(client.RetryAlways)}
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.counters) != 0 {
+		t.Fatalf("counter must not be incremented on success %v", m.counters)
+	}
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/fail", &Request{}), rsp, opts...); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := m.counters["retries_exhausted{method,/fail,status,502}"]; n != 1 || len(m.counters) != 1 {
+		t.Fatalf("invalid counters %v", m.counters)
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"io"
+	"mime/multipart"
+	"sort"
+	"sync"
+)
+
+// FormFile describes file part of multipart form
+type FormFile struct {
+	// Reader provides file content, closed after sent if it implements io.Closer
+	Reader io.Reader
+	// Field name of form field
+	Field string
+	// Filename name of file
+	Filename string
+}
+
+type multipartForm struct {
+	fields map[string]string
+	files  []FormFile
+}
+
+// body returns streamed multipart body and its content type
+func (f multipartForm) body() (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	return newPipeBody(pr, pw, func() error { return f.write(mw) }, f.closeFiles), mw.FormDataContentType()
+}
+
+func (f multipartForm) closeFiles() {
+	for _, file := range f.files {
+		if c, ok := file.Reader.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+func (f multipartForm) write(mw *multipart.Writer) error {
+	defer f.closeFiles()
+
+	keys := make([]string, 0, len(f.fields))
+	for k := range f.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := mw.WriteField(k, f.fields[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range f.files {
+		w, err := mw.CreateFormFile(file.Field, file.Filename)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(w, file.Reader); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// pipeBody starts writer goroutine on first read, so body that never sent not holds goroutine,
+// release called instead of writer if body closed before first read
+type pipeBody struct {
+	pr      *io.PipeReader
+	pw      *io.PipeWriter
+	write   func() error
+	release func()
+	once    sync.Once
+}
+
+func newPipeBody(pr *io.PipeReader, pw *io.PipeWriter, write func() error, release func()) *pipeBody {
+	return &pipeBody{pr: pr, pw: pw, write: write, release: release}
+}
+
+func (b *pipeBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		go func() {
+			b.pw.CloseWithError(b.write())
+		}()
+	})
+	return b.pr.Read(p)
+}
+
+func (b *pipeBody) Close() error {
+	b.once.Do(func() {
+		if b.release != nil {
+			b.release()
+		}
+	})
+	return b.pr.Close()
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestMultipartForm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != -1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		f, fh, err := r.FormFile("upload")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		defer f.Close()
+		buf, _ := io.ReadAll(f)
+		if r.FormValue("title") != "report" || fh.Filename != "report.txt" || string(buf) != "file content" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"uploaded"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	req := c.NewRequest("test", "/upload", nil, WithMultipartForm(
+		map[string]string{"title": "report"},
+		FormFile{Field: "upload", Filename: "report.txt", Reader: strings.NewReader("file content")},
+	))
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "uploaded" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (r *closeRecorder) Close() error {
+	atomic.StoreInt32(&r.closed, 1)
+	return nil
+}
+
+type rejectLimiter struct{}
+
+func (rejectLimiter) Wait(context.Context) error {
+	return fmt.Errorf("rejected")
+}
+
+func TestMultipartFormNotSent(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// request failed before sent, file released without writer
+	file := &closeRecorder{Reader: strings.NewReader("file content")}
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithRateLimiter(rejectLimiter{}))
+	req := c.NewRequest("test", "/upload", nil, WithMultipartForm(nil, FormFile{Field: "upload", Filename: "a.txt", Reader: file}))
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected rate limiter error")
+	}
+	if atomic.LoadInt32(&file.closed) != 1 {
+		t.Fatal("file must be closed")
+	}
+
+	// form files read once, so request not retried
+	c = NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(2), client.Retry(client.RetryAlways))
+	req = c.NewRequest("test", "/upload", nil, WithMultipartForm(nil, FormFile{Field: "upload", Filename: "a.txt", Reader: strings.NewReader("file content")}))
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("multipart body must be sent once, got %d requests", n)
+	}
+}
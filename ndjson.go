@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"go.unistack.org/micro/v3/codec"
+)
+
+// NDJSONContentType is content type of newline-delimited json body
+const NDJSONContentType = "application/x-ndjson"
+
+type ndjsonBody struct {
+	msgs reflect.Value
+}
+
+func newNDJSONBody(msgs interface{}) (ndjsonBody, error) {
+	v := reflect.ValueOf(msgs)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+	case reflect.Chan:
+		if v.Type().ChanDir()&reflect.RecvDir == 0 {
+			return ndjsonBody{}, fmt.Errorf("ndjson body channel %s is send only", v.Type())
+		}
+	default:
+		return ndjsonBody{}, fmt.Errorf("ndjson body must be slice or channel, got %T", msgs)
+	}
+	return ndjsonBody{msgs: v}, nil
+}
+
+// body returns streamed body, each message marshaled by cf and followed by newline,
+// messages read from channel until it closed or ctx done, writer started on first read
+func (b ndjsonBody) body(ctx context.Context, cf codec.Codec) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	return newPipeBody(pr, pw, func() error { return b.write(ctx, cf, pw) }, nil)
+}
+
+func (b ndjsonBody) write(ctx context.Context, cf codec.Codec, w io.Writer) error {
+	if b.msgs.Kind() != reflect.Chan {
+		for i := 0; i < b.msgs.Len(); i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := writeNDJSON(cf, w, b.msgs.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: b.msgs},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return ctx.Err()
+		}
+		if !ok {
+			return nil
+		}
+		if err := writeNDJSON(cf, w, v.Interface()); err != nil {
+			return err
+		}
+	}
+}
+
+func writeNDJSON(cf codec.Codec, w io.Writer, msg interface{}) error {
+	buf, err := cf.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestNDJSON(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != NDJSONContentType || r.ContentLength != -1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		buf, _ := io.ReadAll(r.Body)
+		body = string(buf)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"ok"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+	exp := `{"name":"a","field1":"","ClientID":"","Field2":"","Field3":0}` + "\n" +
+		`{"name":"b","field1":"","ClientID":"","Field2":"","Field3":0}` + "\n"
+
+	req := c.NewRequest("test", "/bulk", nil, WithNDJSON([]*Request{{Name: "a"}, {Name: "b"}}))
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if body != exp {
+		t.Fatalf("invalid slice body %q", body)
+	}
+
+	ch := make(chan *Request)
+	go func() {
+		ch <- &Request{Name: "a"}
+		ch <- &Request{Name: "b"}
+		close(ch)
+	}()
+	req = c.NewRequest("test", "/bulk", nil, WithNDJSON(ch))
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if body != exp {
+		t.Fatalf("invalid channel body %q", body)
+	}
+
+	req = c.NewRequest("test", "/bulk", nil, WithNDJSON("invalid"))
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected error for invalid ndjson messages")
+	}
+}
+
+func TestNDJSONNotSent(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Retries(2), client.Retry(client.RetryAlways))
+
+	ch := make(chan *Request, 2)
+	ch <- &Request{Name: "a"}
+	ch <- &Request{Name: "b"}
+	close(ch)
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/bulk", nil, WithNDJSON(ch)), &Request{}, client.WithAddress(ts.URL)); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("ndjson body must be sent once, got %d requests", n)
+	}
+
+	// dry run request body not read by writer until caller reads it
+	var hreq http.Request
+	ch = make(chan *Request, 1)
+	ch <- &Request{Name: "a"}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/bulk", nil, WithNDJSON(ch)), &Request{}, client.WithAddress(ts.URL), WithDryRun(&hreq)); err != nil {
+		t.Fatal(err)
+	}
+	_ = hreq.Body.Close()
+	if len(ch) != 1 {
+		t.Fatal("channel must not be read for unsent request")
+	}
+}
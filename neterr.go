@@ -0,0 +1,35 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
+	"net"
+	"syscall"
+
+	"go.unistack.org/micro/v3/errors"
+)
+
+// StatusTLSHandshakeFailed code of error returned when tls handshake or certificate verification failed
+const StatusTLSHandshakeFailed = 525
+
+// classifyNetError converts dns, connection and tls errors to errors with distinct codes,
+// returns nil for other errors
+func classifyNetError(err error) error {
+	var dnsErr *net.DNSError
+	var recErr tls.RecordHeaderError
+	var authErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+
+	switch {
+	case stderrors.As(err, &dnsErr):
+		return errors.New("go.micro.client", err.Error(), 502)
+	case stderrors.Is(err, syscall.ECONNREFUSED):
+		return errors.New("go.micro.client", err.Error(), 503)
+	case stderrors.As(err, &recErr), stderrors.As(err, &authErr), stderrors.As(err, &hostErr), stderrors.As(err, &certErr):
+		return errors.New("go.micro.client", err.Error(), StatusTLSHandshakeFailed)
+	}
+
+	return nil
+}
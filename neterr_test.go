@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+func TestNetErrorClassification(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	down := httptest.NewServer(handler)
+	down.Close()
+
+	untrusted := httptest.NewTLSServer(handler)
+	defer untrusted.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	for addr, code := range map[string]int32{
+		"http://host.invalid": 502,
+		down.URL:              503,
+		untrusted.URL:         StatusTLSHandshakeFailed,
+	} {
+		rsp := &Request{}
+		err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(addr), client.WithRetries(0))
+		if verr, ok := err.(*errors.Error); !ok || verr.Code != code {
+			t.Fatalf("%s: expected code %d, got %v", addr, code, err)
+		}
+	}
+}
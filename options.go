@@ -1,11 +1,19 @@
 package http
 
 import (
+	"context"
+	"io"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"time"
 
+	"go.unistack.org/micro/v3/broker"
 	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/logger"
 	"go.unistack.org/micro/v3/metadata"
+	"go.unistack.org/micro/v3/router"
 )
 
 var (
@@ -24,8 +32,49 @@ var (
 	// DefaultMaxSendMsgSize maximum message that client can send
 	// (4 MB).
 	DefaultMaxSendMsgSize = 1024 * 1024 * 4
+
+	// DefaultLogBodySize maximum response body bytes logged on failure
+	// (1 KB).
+	DefaultLogBodySize = 1024
+
+	// DefaultMaxHostTransports maximum transports kept for hosts passed via WithHost,
+	// least recently used one closed on overflow
+	// (64).
+	DefaultMaxHostTransports = 64
 )
 
+// setRequestOption returns a function to setup a request options context with given value
+func setRequestOption(k, v interface{}) client.RequestOption {
+	return func(o *client.RequestOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+type callValuesKey struct{}
+
+type callValue struct {
+	key interface{}
+	val interface{}
+}
+
+// WithValue stores custom value in call options context, so call wrappers can read it
+// from opts.Context, value also available in http request context for signers and
+// round tripper wrappers
+func WithValue(key, val interface{}) client.CallOption {
+	return func(o *client.CallOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		vals, _ := o.Context.Value(callValuesKey{}).([]callValue)
+		o.Context = context.WithValue(o.Context, key, val)
+		// full slice expression, so call options copies not share values
+		o.Context = context.WithValue(o.Context, callValuesKey{}, append(vals[:len(vals):len(vals)], callValue{key: key, val: val}))
+	}
+}
+
 type poolMaxStreams struct{}
 
 // PoolMaxStreams maximum streams on a connectioin
@@ -70,6 +119,14 @@ func HTTPDialer(d *net.Dialer) client.Option {
 	return client.SetOption(httpDialerKey{}, d)
 }
 
+type keepAliveKey struct{}
+
+// KeepAlive sets tcp keep-alive period of client connections including streams,
+// so dead peers are detected on long-lived connections, negative value disables keep-alive
+func KeepAlive(d time.Duration) client.Option {
+	return client.SetOption(keepAliveKey{}, d)
+}
+
 type methodKey struct{}
 
 // Method pass method option to client Call
@@ -84,6 +141,25 @@ func Path(p string) client.CallOption {
 	return client.SetCallOption(pathKey{}, p)
 }
 
+// PathResolution specifies how request path combined with path of client address
+type PathResolution int
+
+const (
+	// PathJoin appends endpoint to address path, so http://host/api with /v1/users
+	// gives http://host/api/v1/users, it is default
+	PathJoin PathResolution = iota
+	// PathReference resolves endpoint against address as url reference, endpoint starting
+	// with / replaces address path and relative endpoint replaces its last segment
+	PathReference
+)
+
+type pathResolutionKey struct{}
+
+// WithPathResolution sets how endpoint or Path option combined with path of client address
+func WithPathResolution(r PathResolution) client.Option {
+	return client.SetOption(pathResolutionKey{}, r)
+}
+
 type bodyKey struct{}
 
 // Body specifies body option to client Call
@@ -91,12 +167,52 @@ func Body(b string) client.CallOption {
 	return client.SetCallOption(bodyKey{}, b)
 }
 
+// WithMethod specifies request method, Method call option takes precedence over it
+func WithMethod(m string) client.RequestOption {
+	return setRequestOption(methodKey{}, m)
+}
+
+// WithPath specifies request path, Path call option takes precedence over it
+func WithPath(p string) client.RequestOption {
+	return setRequestOption(pathKey{}, p)
+}
+
+// WithBody specifies request body field, Body call option takes precedence over it
+func WithBody(b string) client.RequestOption {
+	return setRequestOption(bodyKey{}, b)
+}
+
 type errorMapKey struct{}
 
+// ErrorMap pass map of status code to error type to client Call, response with error
+// status decoded to matched type, "default" key used for not listed codes
 func ErrorMap(m map[string]interface{}) client.CallOption {
 	return client.SetCallOption(errorMapKey{}, m)
 }
 
+// ErrorDecoder converts error response to error
+type ErrorDecoder func(status int, header http.Header, body []byte) error
+
+type errorDecoderKey struct{}
+
+// WithErrorDecoder pass func to client Call that converts response with status >= 400
+// to error, it takes precedence over ErrorMap
+func WithErrorDecoder(fn ErrorDecoder) client.CallOption {
+	return client.SetCallOption(errorDecoderKey{}, fn)
+}
+
+// EnvelopeStatus returns status encoded in response body of api that always responds with 200,
+// like {"code": 404}, zero or code below 400 means success
+type EnvelopeStatus func(body []byte) (code int, err error)
+
+type envelopeStatusKey struct{}
+
+// WithEnvelopeStatus pass func to client Call to read status from successful response body,
+// error status handled like http error status, so ErrorMap and WithErrorDecoder applied to it
+func WithEnvelopeStatus(fn EnvelopeStatus) client.CallOption {
+	return client.SetCallOption(envelopeStatusKey{}, fn)
+}
+
 type structTagsKey struct{}
 
 // StructTags pass tags slice option to client Call
@@ -124,3 +240,711 @@ type headerKey struct{}
 func Header(headers ...string) client.CallOption {
 	return client.SetCallOption(headerKey{}, headers)
 }
+
+type responseMetadataKey struct{}
+
+// ResponseMetadata pass metadata pointer to client Call to fill it with response headers
+func ResponseMetadata(md *metadata.Metadata) client.CallOption {
+	return client.SetCallOption(responseMetadataKey{}, md)
+}
+
+type responseStatusKey struct{}
+
+// ResponseStatus pass int pointer to client Call to fill it with original response status code
+func ResponseStatus(status *int) client.CallOption {
+	return client.SetCallOption(responseStatusKey{}, status)
+}
+
+type statusRewriterKey struct{}
+
+// WithStatusRewriter pass func to client Call that maps response status code
+// to normalized one before response processed
+func WithStatusRewriter(fn func(int) int) client.CallOption {
+	return client.SetCallOption(statusRewriterKey{}, fn)
+}
+
+type responseTrailerKey struct{}
+
+// ResponseTrailer pass metadata pointer to client Call to fill it with response trailers,
+// trailers sent by server after body, so remaining body is read before filling
+func ResponseTrailer(md *metadata.Metadata) client.CallOption {
+	return client.SetCallOption(responseTrailerKey{}, md)
+}
+
+type requestIDHeaderKey struct{}
+
+// RequestIDHeader enables request id generation, header with passed name
+// is filled with random uuid if it not already present in request metadata
+func RequestIDHeader(name string) client.Option {
+	return client.SetOption(requestIDHeaderKey{}, name)
+}
+
+type slowCallKey struct{}
+
+type slowCall struct {
+	handler   func([]byte)
+	threshold time.Duration
+}
+
+// WithSlowCallThreshold pass threshold to client Call, if call does not complete
+// after it, handler is invoked with stack dump of all goroutines taken at that moment,
+// so it shows where call blocked, call itself continues
+func WithSlowCallThreshold(d time.Duration, handler func(dump []byte)) client.CallOption {
+	return client.SetCallOption(slowCallKey{}, slowCall{threshold: d, handler: handler})
+}
+
+type maxConcurrentPerHostKey struct{}
+
+// WithMaxConcurrentPerHost limits number of concurrent requests to single host,
+// waiting requests are dequeued by priority
+func WithMaxConcurrentPerHost(n int) client.Option {
+	return client.SetOption(maxConcurrentPerHostKey{}, n)
+}
+
+type adaptiveConcurrencyKey struct{}
+
+type adaptiveConcurrency struct {
+	min int
+	max int
+}
+
+// AdaptiveConcurrency limits number of in-flight requests of client, limit starts from max and
+// halved on 503, 429 and 504 responses and transport timeouts down to min, it slowly grows back
+// on success, timeouts of client own limiter waits are not counted
+func AdaptiveConcurrency(min, max int) client.Option {
+	return client.SetOption(adaptiveConcurrencyKey{}, adaptiveConcurrency{min: min, max: max})
+}
+
+type priorityKey struct{}
+
+// WithPriority pass priority to client Call, used when requests wait for limiter
+// slot, higher level dequeued first
+func WithPriority(level int) client.CallOption {
+	return client.SetCallOption(priorityKey{}, level)
+}
+
+// CircuitBreaker is consulted before each request, when Allow returns false
+// request fails without network call, results of requests passed to Report
+type CircuitBreaker interface {
+	Allow(endpoint string) bool
+	Report(endpoint string, err error)
+}
+
+type circuitBreakerKey struct{}
+
+// WithCircuitBreaker pass CircuitBreaker to client, endpoint is
+// formed from request service and endpoint
+func WithCircuitBreaker(cb CircuitBreaker) client.Option {
+	return client.SetOption(circuitBreakerKey{}, cb)
+}
+
+type arrayRootFieldKey struct{}
+
+// WithArrayRootField pass slice field name to client Call, if response body is array
+// it decoded to this field of response struct
+func WithArrayRootField(name string) client.CallOption {
+	return client.SetCallOption(arrayRootFieldKey{}, name)
+}
+
+type connAcquireTimeoutKey struct{}
+
+// WithConnAcquireTimeout pass timeout to client Call, if connection is not
+// acquired from pool or dialed in time, call fails with ErrConnAcquireTimeout
+func WithConnAcquireTimeout(d time.Duration) client.CallOption {
+	return client.SetCallOption(connAcquireTimeoutKey{}, d)
+}
+
+type grpcWebKey struct{}
+
+// WithGRPCWeb sends message with grpc length prefix and grpc-web content type, response
+// message deframed and non zero grpc-status returned as error, so grpc-web backends can be called
+func WithGRPCWeb() client.CallOption {
+	return client.SetCallOption(grpcWebKey{}, true)
+}
+
+type responseHeaderTimeoutKey struct{}
+
+// ResponseHeaderTimeout sets transport timeout of waiting for response headers after request written
+func ResponseHeaderTimeout(d time.Duration) client.Option {
+	return client.SetOption(responseHeaderTimeoutKey{}, d)
+}
+
+// WithResponseHeaderTimeout pass timeout to client Call, if response headers are not received in time
+// after request written, call fails with ErrResponseHeaderTimeout, body read limited only by request timeout
+func WithResponseHeaderTimeout(d time.Duration) client.CallOption {
+	return client.SetCallOption(responseHeaderTimeoutKey{}, d)
+}
+
+type bodyReaderKey struct{}
+
+// WithBodyReader pass reader to request, its content streamed as request body
+// with chunked transfer encoding without codec marshaling, reader is read once,
+// so such request is not retried or hedged
+func WithBodyReader(r io.Reader) client.RequestOption {
+	return setRequestOption(bodyReaderKey{}, r)
+}
+
+type multipartFormKey struct{}
+
+// WithMultipartForm pass form fields and files to request, its sent as streamed
+// multipart/form-data body without codec marshaling, files are read once,
+// so such request is not retried or hedged
+func WithMultipartForm(fields map[string]string, files ...FormFile) client.RequestOption {
+	return setRequestOption(multipartFormKey{}, multipartForm{fields: fields, files: files})
+}
+
+type ndjsonKey struct{}
+
+// WithNDJSON pass slice or channel of messages to request, each message marshaled
+// by request codec and sent as newline-delimited json with application/x-ndjson
+// content type, channel messages streamed until channel closed, body is streamed once,
+// so such request is not retried or hedged
+func WithNDJSON(msgs interface{}) client.RequestOption {
+	return setRequestOption(ndjsonKey{}, msgs)
+}
+
+type contentLengthKey struct{}
+
+// WithContentLength sets request Content-Length, negative n sends body
+// without Content-Length using chunked transfer encoding
+func WithContentLength(n int64) client.RequestOption {
+	return setRequestOption(contentLengthKey{}, n)
+}
+
+type rawResponseKey struct{}
+
+// WithRawResponse pass response pointer to client Call, response is not parsed
+// and returned with unread body, caller must close it
+func WithRawResponse(rsp **http.Response) client.CallOption {
+	return client.SetCallOption(rawResponseKey{}, rsp)
+}
+
+type streamResponseKey struct{}
+
+// WithStreamResponse enables copying successful response body directly to rsp
+// if it implements io.Writer, so large body not buffered in memory
+func WithStreamResponse() client.CallOption {
+	return client.SetCallOption(streamResponseKey{}, true)
+}
+
+type publishBrokerKey struct{}
+
+type publishBroker struct {
+	broker broker.Broker
+}
+
+// WithBroker pass broker to client Publish, it used instead of client broker
+func WithBroker(b broker.Broker) client.PublishOption {
+	return client.SetPublishOption(publishBrokerKey{}, publishBroker{broker: b})
+}
+
+// WithPublishErrorMap pass error map to Publish, error response of webhook delivery decoded
+// to error mapped by response status like ErrorMap does for Call
+func WithPublishErrorMap(m map[string]interface{}) client.PublishOption {
+	return client.SetPublishOption(errorMapKey{}, m)
+}
+
+type publishWebhookKey struct{}
+
+// PublishWebhook makes client Publish to send messages as http POST requests
+// to url returned by fn for message topic, instead of broker
+func PublishWebhook(fn func(topic string) string) client.Option {
+	return client.SetOption(publishWebhookKey{}, fn)
+}
+
+type maxIdleConnsKey struct{}
+
+// MaxIdleConns sets maximum idle connections of client transport
+func MaxIdleConns(n int) client.Option {
+	return client.SetOption(maxIdleConnsKey{}, n)
+}
+
+type maxIdleConnsPerHostKey struct{}
+
+// MaxIdleConnsPerHost sets maximum idle connections per host of client transport
+func MaxIdleConnsPerHost(n int) client.Option {
+	return client.SetOption(maxIdleConnsPerHostKey{}, n)
+}
+
+type idleConnTimeoutKey struct{}
+
+// IdleConnTimeout sets how long idle connection kept in client transport pool
+func IdleConnTimeout(d time.Duration) client.Option {
+	return client.SetOption(idleConnTimeoutKey{}, d)
+}
+
+type contentMD5Key struct{}
+
+// WithContentMD5 enables Content-MD5 header with md5 of request body
+func WithContentMD5() client.CallOption {
+	return client.SetCallOption(contentMD5Key{}, true)
+}
+
+type elementHandlerKey struct{}
+
+// WithElementHandler pass handler to client Call, response body with array or newline
+// delimited messages decoded element by element into values created by newElem and
+// passed to handler, on decode error already decoded elements are delivered
+func WithElementHandler(newElem func() interface{}, handler func(elem interface{}) error) client.CallOption {
+	return client.SetCallOption(elementHandlerKey{}, elementHandler{newElem: newElem, handler: handler})
+}
+
+type http2Key struct{}
+
+// HTTP2 enables http2 with prior knowledge for plain text requests (h2c, not proxied),
+// tls requests negotiate http2 or http/1.1, streams created by client Stream always use http/1.1,
+// Init returns error if http2 can not be enabled
+func HTTP2(b bool) client.Option {
+	return client.SetOption(http2Key{}, b)
+}
+
+type maxRedirectsKey struct{}
+
+// WithMaxRedirects limits number of redirects followed by client
+func WithMaxRedirects(n int) client.Option {
+	return client.SetOption(maxRedirectsKey{}, n)
+}
+
+type pathMapperKey struct{}
+
+// PathMapper pass func to client, it returns http method and path for request service and endpoint,
+// Method and Path call options have higher priority
+func PathMapper(fn func(service, endpoint string) (method, path string)) client.Option {
+	return client.SetOption(pathMapperKey{}, fn)
+}
+
+type timeFormatKey struct{}
+
+// WithTimeFormat pass time layout to client Call, time.Time fields of json request
+// and response messages encoded and decoded with it, other content types not changed
+func WithTimeFormat(layout string) client.CallOption {
+	return client.SetCallOption(timeFormatKey{}, layout)
+}
+
+type warningHandlerKey struct{}
+
+// WithWarningHandler pass handler to client Call that receives all Warning response header values
+func WithWarningHandler(fn func(warnings []string)) client.CallOption {
+	return client.SetCallOption(warningHandlerKey{}, fn)
+}
+
+type cookieJarKey struct{}
+
+// CookieJar pass http.CookieJar to client to store and resend cookies between calls
+func CookieJar(jar http.CookieJar) client.Option {
+	return client.SetOption(cookieJarKey{}, jar)
+}
+
+// EnableCookies installs default in-memory cookie jar to client
+func EnableCookies() client.Option {
+	jar, _ := cookiejar.New(nil)
+	return CookieJar(jar)
+}
+
+type callCookiesKey struct{}
+
+// WithCookie pass cookies to client Call
+func WithCookie(cookies ...*http.Cookie) client.CallOption {
+	return client.SetCallOption(callCookiesKey{}, cookies)
+}
+
+type requestLoggerKey struct{}
+
+type requestLogger struct {
+	logger logger.Logger
+}
+
+// RequestLogger enables request logging with given logger, if nil client logger used
+func RequestLogger(l logger.Logger) client.Option {
+	return client.SetOption(requestLoggerKey{}, requestLogger{l})
+}
+
+type logBodyKey struct{}
+
+// LogBody enables logging response body snippet for failed calls, it may contain sensitive data
+func LogBody(b bool) client.Option {
+	return client.SetOption(logBodyKey{}, b)
+}
+
+type roundTripperKey struct{}
+
+// WithRoundTripper pass http.RoundTripper to client Call that used to execute request instead of client transport
+func WithRoundTripper(rt http.RoundTripper) client.CallOption {
+	return client.SetCallOption(roundTripperKey{}, rt)
+}
+
+// RoundTripperWrapper wraps http.RoundTripper, for example to add http middleware
+type RoundTripperWrapper func(http.RoundTripper) http.RoundTripper
+
+type roundTripperWrappersKey struct{}
+
+// WrapRoundTripper adds transport wrappers applied to unary calls,
+// first wrapper is the outermost one
+func WrapRoundTripper(w ...RoundTripperWrapper) client.Option {
+	return func(o *client.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		prev, _ := o.Context.Value(roundTripperWrappersKey{}).([]RoundTripperWrapper)
+		o.Context = context.WithValue(o.Context, roundTripperWrappersKey{}, append(prev[:len(prev):len(prev)], w...))
+	}
+}
+
+type assumeContentTypeKey struct{}
+
+// WithAssumeContentType pass content type to client Call that used to decode response without Content-Type header
+func WithAssumeContentType(ct string) client.CallOption {
+	return client.SetCallOption(assumeContentTypeKey{}, ct)
+}
+
+type reselectPerRetryKey struct{}
+
+// WithReselectPerRetry makes client Call to lookup and select routes again on each retry
+func WithReselectPerRetry() client.CallOption {
+	return client.SetCallOption(reselectPerRetryKey{}, true)
+}
+
+type upgradeKey struct{}
+
+// WithUpgrade pass protocol and Upgraded to client Call, request sent with Upgrade header
+// and on 101 Switching Protocols response Upgraded filled with connection instead of decoding body,
+// caller must close it
+func WithUpgrade(protocol string, u *Upgraded) client.CallOption {
+	return client.SetCallOption(upgradeKey{}, upgrade{upgraded: u, protocol: protocol})
+}
+
+type maxRequestSizeKey struct{}
+
+// WithMaxRequestSize pass maximum request body size to client Call, request with bigger body
+// rejected before sending, streamed body aborted when limit exceeded
+func WithMaxRequestSize(n int64) client.CallOption {
+	return client.SetCallOption(maxRequestSizeKey{}, n)
+}
+
+// TimeoutFormat specifies format of Timeout header value
+type TimeoutFormat int
+
+const (
+	// TimeoutNanoseconds sends timeout as integer nanoseconds
+	TimeoutNanoseconds TimeoutFormat = iota
+	// TimeoutSeconds sends timeout as integer seconds rounded up
+	TimeoutSeconds
+	// TimeoutDuration sends timeout as go duration string like 1.5s
+	TimeoutDuration
+)
+
+type timeoutFormatKey struct{}
+
+// WithTimeoutFormat sets format of Timeout header value, zero timeout is never sent
+func WithTimeoutFormat(f TimeoutFormat) client.Option {
+	return client.SetOption(timeoutFormatKey{}, f)
+}
+
+type hedgingKey struct{}
+
+// WithHedging pass delay to client Call, if response not received in delay, same request
+// sent to next selected node other than first one, first successful response and its
+// response metadata, status and trailer used and other request canceled,
+// canceled request recorded to selector with ErrHedgedCallCanceled
+func WithHedging(delay time.Duration) client.CallOption {
+	return client.SetCallOption(hedgingKey{}, delay)
+}
+
+type broadcastQuorumKey struct{}
+
+// WithBroadcastQuorum pass minimum number of successful responses to Broadcast
+func WithBroadcastQuorum(n int) client.CallOption {
+	return client.SetCallOption(broadcastQuorumKey{}, n)
+}
+
+// broadcastKey holds *BroadcastResult, set by Broadcast to turn Call into broadcast
+type broadcastKey struct{}
+
+type hostKey struct{}
+
+// WithHost pass host to client Call, it sent in Host header and used as tls server name
+// instead of node address
+func WithHost(host string) client.CallOption {
+	return client.SetCallOption(hostKey{}, host)
+}
+
+// acceptKey holds Accept header of internal fallback call
+type acceptKey struct{}
+
+// nestedCallKey marks call repeated internally
+type nestedCallKey struct{}
+
+type acceptFallbackKey struct{}
+
+// WithAcceptFallback pass content types to client Call, on 406 Not Acceptable response
+// request sent again with Accept header set to next type until one succeeds
+func WithAcceptFallback(types ...string) client.CallOption {
+	return client.SetCallOption(acceptFallbackKey{}, types)
+}
+
+type bodyBufferBudgetKey struct{}
+
+// WithBodyBufferBudget limits total size of marshaled request bodies held in memory
+// by in-flight requests, new requests wait until budget available
+func WithBodyBufferBudget(n int64) client.Option {
+	return client.SetOption(bodyBufferBudgetKey{}, n)
+}
+
+type conflictRetryKey struct{}
+
+type conflictRetry struct {
+	fn      func(context.Context) (client.Request, error)
+	retries int
+}
+
+// WithConflictRetry pass func to client Call, on 409 Conflict response it called to build
+// updated request that sent instead of original, up to retries times
+func WithConflictRetry(fn func(ctx context.Context) (client.Request, error), retries int) client.CallOption {
+	return client.SetCallOption(conflictRetryKey{}, conflictRetry{fn: fn, retries: retries})
+}
+
+type proxyKey struct{}
+
+// Proxy sets http proxy url for outbound requests, by default proxy taken from
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+// it differs from micro proxy that overrides service address
+func Proxy(u string) client.Option {
+	return client.SetOption(proxyKey{}, u)
+}
+
+type sessionKey struct{}
+
+// WithSession pass session to client Call, calls with the same session sent to the same node while it available
+func WithSession(s *Session) client.CallOption {
+	return client.SetCallOption(sessionKey{}, s)
+}
+
+// DeadlineFormat specifies format of deadline header value
+type DeadlineFormat int
+
+const (
+	// DeadlineRFC3339 sends deadline as RFC3339 timestamp with nanoseconds in UTC
+	DeadlineRFC3339 DeadlineFormat = iota
+	// DeadlineUnixMillis sends deadline as unix time in milliseconds
+	DeadlineUnixMillis
+)
+
+type deadlineHeaderKey struct{}
+
+type deadlineHeader struct {
+	name   string
+	format DeadlineFormat
+}
+
+// DeadlineHeader enables sending context deadline as absolute time in header with given name,
+// X-Deadline used if name is empty
+func DeadlineHeader(name string, format DeadlineFormat) client.Option {
+	if name == "" {
+		name = "X-Deadline"
+	}
+	return client.SetOption(deadlineHeaderKey{}, deadlineHeader{name: name, format: format})
+}
+
+type retryExhaustionCounterKey struct{}
+
+// WithRetryExhaustionCounter sets name of client meter counter incremented when call
+// failed after all retries, counter has method and status labels
+func WithRetryExhaustionCounter(name string) client.Option {
+	return client.SetOption(retryExhaustionCounterKey{}, name)
+}
+
+type noRequestTimeoutKey struct{}
+
+// NoRequestTimeout disables context timeout created by client Call when context has no deadline,
+// so call runs until server responds or caller context canceled
+func NoRequestTimeout() client.Option {
+	return client.SetOption(noRequestTimeoutKey{}, true)
+}
+
+// WithNoRequestTimeout disables context timeout created by client Call for single call
+func WithNoRequestTimeout() client.CallOption {
+	return client.SetCallOption(noRequestTimeoutKey{}, true)
+}
+
+type streamIdleTimeoutKey struct{}
+
+// StreamIdleTimeout sets how long stream Send and Recv wait on connection, deadline
+// is reset for each message, so long-lived streams with periodic messages are kept,
+// by default request timeout used
+func StreamIdleTimeout(d time.Duration) client.Option {
+	return client.SetOption(streamIdleTimeoutKey{}, d)
+}
+
+// WithStreamIdleTimeout sets stream idle timeout for single stream
+func WithStreamIdleTimeout(d time.Duration) client.CallOption {
+	return client.SetCallOption(streamIdleTimeoutKey{}, d)
+}
+
+type selectedNodeKey struct{}
+
+// WithSelectedNode pass string pointer to client Call to fill it with node address
+// that served response, on failure last attempted node is set
+func WithSelectedNode(node *string) client.CallOption {
+	return client.SetCallOption(selectedNodeKey{}, node)
+}
+
+type allowedMethodsKey struct{}
+
+// WithAllowedMethods pass string slice pointer to client Call to fill it with methods
+// from response Allow header, useful for OPTIONS requests
+func WithAllowedMethods(methods *[]string) client.CallOption {
+	return client.SetCallOption(allowedMethodsKey{}, methods)
+}
+
+type attemptsKey struct{}
+
+// WithAttempts pass int pointer to client Call to fill it with number of attempts made,
+// value greater than 1 means that call was retried
+func WithAttempts(n *int) client.CallOption {
+	return client.SetCallOption(attemptsKey{}, n)
+}
+
+type routeLabelKey struct{}
+
+type routeLabel struct {
+	key string
+	val string
+}
+
+// RouteLabel sets route metadata label used to filter routes returned by router, only
+// routes with this label selected, default protocol=http also keeps routes without label,
+// empty key disables filtering
+func RouteLabel(key, val string) client.Option {
+	return client.SetOption(routeLabelKey{}, routeLabel{key: key, val: val})
+}
+
+type requestSignerKey struct{}
+
+// WithRequestSigner sets signer called for each request after body marshalled and before it sent
+func WithRequestSigner(s RequestSigner) client.Option {
+	return client.SetOption(requestSignerKey{}, s)
+}
+
+type preserveHeaderCaseKey struct{}
+
+// PreserveHeaderCase makes client send metadata header keys as is without canonicalization,
+// needed for servers that require exact header casing
+func PreserveHeaderCase(b bool) client.Option {
+	return client.SetOption(preserveHeaderCaseKey{}, b)
+}
+
+type rateLimiterKey struct{}
+
+// WithRateLimiter sets limiter consulted by client before each request
+func WithRateLimiter(l RateLimiter) client.Option {
+	return client.SetOption(rateLimiterKey{}, l)
+}
+
+type keyedRateLimiterKey struct{}
+
+type keyedRateLimiterOption struct {
+	key        func(req client.Request) string
+	newLimiter func(key string) RateLimiter
+}
+
+// WithKeyedRateLimiter sets per key rate limiting, limiter created by newLimiter
+// for each key returned by key func, for example request endpoint
+func WithKeyedRateLimiter(key func(req client.Request) string, newLimiter func(key string) RateLimiter) client.Option {
+	return client.SetOption(keyedRateLimiterKey{}, keyedRateLimiterOption{key: key, newLimiter: newLimiter})
+}
+
+type routeFilterKey struct{}
+
+// WithFilter pass func to client Call that filters routes before node selected,
+// it is not applied if lookup func passed via client.Lookup option
+func WithFilter(fn func([]router.Route) []router.Route) client.CallOption {
+	return client.SetCallOption(routeFilterKey{}, fn)
+}
+
+type metadataFilterKey struct{}
+
+// MetadataFilter sets metadata keys sent as request headers, if allow is not empty
+// only listed keys sent, keys from deny never sent, keys compared case insensitive
+func MetadataFilter(allow []string, deny []string) client.Option {
+	return client.SetOption(metadataFilterKey{}, newMetadataFilter(allow, deny))
+}
+
+type headerMapperKey struct{}
+
+// HeaderMapper sets func that maps metadata key to request header name, key dropped
+// if send is false, it applied after MetadataFilter
+func HeaderMapper(fn func(metaKey string) (headerName string, send bool)) client.Option {
+	return client.SetOption(headerMapperKey{}, fn)
+}
+
+type retryIdempotentOnlyKey struct{}
+
+// RetryIdempotentOnly makes client retry only requests with idempotent methods like GET, PUT or DELETE
+// and requests marked with WithIdempotent
+func RetryIdempotentOnly(b bool) client.Option {
+	return client.SetOption(retryIdempotentOnlyKey{}, b)
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks request as idempotent or not regardless of its method
+func WithIdempotent(b bool) client.RequestOption {
+	return setRequestOption(idempotentKey{}, b)
+}
+
+type clientTraceKey struct{}
+
+// WithClientTrace sets httptrace callbacks called for each request, for example
+// to get connection reuse, dns lookup and tls handshake info
+func WithClientTrace(t *httptrace.ClientTrace) client.Option {
+	return client.SetOption(clientTraceKey{}, t)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun pass request pointer to client Call, it filled with request as it would be sent
+// and call returns without sending it, caller must close request body
+func WithDryRun(hreq *http.Request) client.CallOption {
+	return client.SetCallOption(dryRunKey{}, hreq)
+}
+
+type urlKey struct{}
+
+// WithURL pass absolute url to client Call, request sent to it as is
+// without routing and path composition
+func WithURL(u string) client.CallOption {
+	return client.SetCallOption(urlKey{}, u)
+}
+
+type responseCacheKey struct{}
+
+// WithResponseCache sets cache for GET and HEAD responses, response cached if
+// Cache-Control or Expires allow it, no-store, no-cache and private directives respected,
+// responses to requests with Authorization or Cookie cached only if marked public
+func WithResponseCache(c Cache) client.Option {
+	return client.SetOption(responseCacheKey{}, c)
+}
+
+type singleFlightKey struct{}
+
+// SingleFlight enables coalescing of concurrent identical GET and HEAD requests, only one
+// request with same method, url and headers sent at a time and its response shared,
+// headers unique for each call like timeout, deadline, request id and trace context not compared
+func SingleFlight(b bool) client.Option {
+	return client.SetOption(singleFlightKey{}, b)
+}
+
+type singleFlightKeyFuncKey struct{}
+
+// SingleFlightKey sets func returning key of request for SingleFlight, requests with same key
+// share response, it needed if request signer adds per call headers
+func SingleFlightKey(fn func(*http.Request) string) client.Option {
+	return client.SetOption(singleFlightKeyFuncKey{}, fn)
+}
+
+type closeKey struct{}
+
+// WithClose sets Connection: close for request, so connection is not reused
+func WithClose(b bool) client.RequestOption {
+	return setRequestOption(closeKey{}, b)
+}
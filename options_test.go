@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/metadata"
+)
+
+func TestOptionsContext(t *testing.T) {
+	httpcli := &http.Client{}
+	dialer := &net.Dialer{}
+	errmap := map[string]interface{}{"404": &Error{}}
+	md := metadata.Metadata{"X-Test": "test"}
+
+	copts := client.NewCallOptions(
+		Method(http.MethodPut),
+		Path("/path"),
+		Body("name"),
+		ErrorMap(errmap),
+		StructTags([]string{"json"}),
+		Metadata(md),
+		Header("X-Header", "true"),
+		Cookie("cookie", "true"),
+	)
+	for key, val := range map[interface{}]interface{}{
+		methodKey{}:     http.MethodPut,
+		pathKey{}:       "/path",
+		bodyKey{}:       "name",
+		errorMapKey{}:   errmap,
+		structTagsKey{}: []string{"json"},
+		metadataKey{}:   md,
+		headerKey{}:     []string{"X-Header", "true"},
+		cookieKey{}:     []string{"cookie", "true"},
+	} {
+		if v := copts.Context.Value(key); !reflect.DeepEqual(v, val) {
+			t.Fatalf("call option %T: expected %v, got %v", key, val, v)
+		}
+	}
+
+	ropts := client.NewRequestOptions(WithMethod(http.MethodPatch), WithPath("/request"), WithBody("field1"))
+	for key, val := range map[interface{}]interface{}{
+		methodKey{}: http.MethodPatch,
+		pathKey{}:   "/request",
+		bodyKey{}:   "field1",
+	} {
+		if v := ropts.Context.Value(key); v != val {
+			t.Fatalf("request option %T: expected %v, got %v", key, val, v)
+		}
+	}
+
+	opts := client.NewOptions(HTTPClient(httpcli), HTTPDialer(dialer))
+	if v := opts.Context.Value(httpClientKey{}); v != httpcli {
+		t.Fatalf("invalid http client %v", v)
+	}
+	if v := opts.Context.Value(httpDialerKey{}); v != dialer {
+		t.Fatalf("invalid http dialer %v", v)
+	}
+}
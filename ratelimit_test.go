@@ -0,0 +1,61 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+// tokenLimiter allows n requests and rejects others
+type tokenLimiter struct {
+	n int
+}
+
+func (l *tokenLimiter) Wait(ctx context.Context) error {
+	if l.n == 0 {
+		return fmt.Errorf("no tokens")
+	}
+	l.n--
+	return nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		WithRateLimiter(&tokenLimiter{n: 3}),
+		WithKeyedRateLimiter(func(req client.Request) string {
+			return req.Endpoint()
+		}, func(key string) RateLimiter {
+			return &tokenLimiter{n: 1}
+		}),
+	)
+
+	call := func(endpoint string) error {
+		return c.Call(context.TODO(), c.NewRequest("test", endpoint, &Request{}), &Request{}, client.WithAddress(ts.URL))
+	}
+
+	if err := call("/first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/first"); err == nil || errors.FromError(err).Code != http.StatusTooManyRequests {
+		t.Fatalf("expected endpoint rate limit error, got %v", err)
+	}
+	if err := call("/second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/third"); err == nil || errors.FromError(err).Code != http.StatusTooManyRequests {
+		t.Fatalf("expected global rate limit error, got %v", err)
+	}
+}
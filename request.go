@@ -1,6 +1,8 @@
 package http
 
 import (
+	"context"
+
 	"go.unistack.org/micro/v3/client"
 	"go.unistack.org/micro/v3/codec"
 )
@@ -55,3 +57,11 @@ func (h *httpRequest) Body() interface{} {
 func (h *httpRequest) Stream() bool {
 	return h.opts.Stream
 }
+
+// requestContext returns context from request options
+func requestContext(req client.Request) context.Context {
+	if r, ok := req.(*httpRequest); ok && r.opts.Context != nil {
+		return r.opts.Context
+	}
+	return context.Background()
+}
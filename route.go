@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/errors"
+	"go.unistack.org/micro/v3/router"
+	"go.unistack.org/micro/v3/selector"
+)
+
+var (
+	// DefaultRouteLabel is the route metadata key used to filter routes
+	DefaultRouteLabel = "protocol"
+	// DefaultRouteLabelValue is the route metadata value used to filter routes
+	DefaultRouteLabelValue = "http"
+)
+
+// filterLabel returns routes with label key equal to val, routes without label
+// kept only if not strict, as router may not provide metadata for default label
+func filterLabel(routes []router.Route, key, val string, strict bool) []router.Route {
+	if key == "" {
+		return routes
+	}
+	filtered := routes[:0:0]
+	for _, r := range routes {
+		if v, ok := r.Metadata[key]; (ok && v != val) || (!ok && strict) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// isDefaultLookup checks that lookup func not overridden by client.Lookup option
+func isDefaultLookup(fn client.LookupFunc) bool {
+	return fn != nil && reflect.ValueOf(fn).Pointer() == reflect.ValueOf(client.LookupRoute).Pointer()
+}
+
+// lookupRoute looks up service routes via router and filters out non http ones,
+// routes also filtered by WithFilter call option
+func (h *httpClient) lookupRoute(ctx context.Context, req client.Request, opts client.CallOptions) ([]string, error) {
+	filter, _ := opts.Context.Value(routeFilterKey{}).(func([]router.Route) []router.Route)
+
+	if len(opts.Address) > 0 || opts.Router == nil {
+		addrs, err := client.LookupRoute(ctx, req, opts)
+		if err != nil || filter == nil {
+			return addrs, err
+		}
+		// addresses passed as is, so routes have no metadata
+		routes := make([]router.Route, 0, len(addrs))
+		for _, addr := range addrs {
+			routes = append(routes, router.Route{Service: req.Service(), Address: addr})
+		}
+		return routeAddresses(req, filter(routes))
+	}
+
+	query := []router.QueryOption{router.QueryService(req.Service())}
+	if len(opts.Network) > 0 {
+		query = append(query, router.QueryNetwork(opts.Network))
+	}
+
+	routes, err := opts.Router.Lookup(query...)
+	if err != nil {
+		return nil, err
+	}
+
+	// label set by RouteLabel option required, default one may be missing
+	key, val, strict := DefaultRouteLabel, DefaultRouteLabelValue, false
+	if l, ok := h.opts.Context.Value(routeLabelKey{}).(routeLabel); ok {
+		key, val, strict = l.key, l.val, true
+	}
+
+	routes = filterLabel(routes, key, val, strict)
+	if filter != nil {
+		routes = filter(routes)
+	}
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Metric < routes[j].Metric })
+
+	return routeAddresses(req, routes)
+}
+
+func routeAddresses(req client.Request, routes []router.Route) ([]string, error) {
+	if len(routes) == 0 {
+		return nil, errors.InternalServerError("go.micro.client", "service %s: %s", req.Service(), selector.ErrNoneAvailable.Error())
+	}
+
+	addrs := make([]string, 0, len(routes))
+	for _, r := range routes {
+		addrs = append(addrs, r.Address)
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/router"
+)
+
+type testRouter struct {
+	router.Router
+	routes []router.Route
+}
+
+func (r *testRouter) Lookup(opts ...router.QueryOption) ([]router.Route, error) {
+	return r.routes, nil
+}
+
+func TestFilterLabel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"http"}`))
+	}))
+	defer ts.Close()
+
+	r := &testRouter{routes: []router.Route{
+		{Service: "test", Address: "127.0.0.1:1", Metadata: map[string]string{"protocol": "grpc"}},
+		{Service: "test", Address: ts.URL, Metadata: map[string]string{"protocol": "http"}},
+	}}
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Router(r))
+
+	for i := 0; i < 3; i++ {
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != "http" {
+			t.Fatalf("invalid response %#+v", rsp)
+		}
+	}
+
+	r.routes = r.routes[:1]
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}); err == nil {
+		t.Fatal("expected error without http routes")
+	}
+
+	// route without label passes default filter only
+	r.routes = []router.Route{{Service: "test", Address: ts.URL}}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}); err != nil {
+		t.Fatal(err)
+	}
+	c = NewClient(client.Codec("application/json", codec.NewCodec()), client.Router(r), RouteLabel("zone", "a"))
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}); err == nil {
+		t.Fatal("expected error without labeled routes")
+	}
+}
+
+func TestRouteFilter(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"` + name + `"}`))
+		})
+	}
+	v1 := httptest.NewServer(handler("v1"))
+	defer v1.Close()
+	v2 := httptest.NewServer(handler("v2"))
+	defer v2.Close()
+
+	r := &testRouter{routes: []router.Route{
+		{Service: "test", Address: v1.URL, Metadata: map[string]string{"version": "v1"}},
+		{Service: "test", Address: v2.URL, Metadata: map[string]string{"version": "v2"}},
+	}}
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Router(r))
+
+	canary := WithFilter(func(routes []router.Route) []router.Route {
+		var filtered []router.Route
+		for _, route := range routes {
+			if route.Metadata["version"] == "v2" {
+				filtered = append(filtered, route)
+			}
+		}
+		return filtered
+	})
+
+	for i := 0; i < 3; i++ {
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, canary); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != "v2" {
+			t.Fatalf("invalid response %#+v", rsp)
+		}
+	}
+
+	// filter applied to addresses passed to call
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(v1.URL), canary); err == nil {
+		t.Fatal("expected error for filtered addresses")
+	}
+}
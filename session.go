@@ -0,0 +1,57 @@
+package http
+
+import (
+	"sync"
+
+	"go.unistack.org/micro/v3/errors"
+)
+
+// Session keeps node used by calls of logical session, zero value is ready to use
+type Session struct {
+	node string
+	sync.RWMutex
+}
+
+// NewSession returns new empty session
+func NewSession() *Session {
+	return &Session{}
+}
+
+// Node returns node used by session calls, empty if no successful calls made
+func (s *Session) Node() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.node
+}
+
+// pick returns session node if it present in routes
+func (s *Session) pick(routes []string) string {
+	s.RLock()
+	defer s.RUnlock()
+	if s.node == "" {
+		return ""
+	}
+	for _, route := range routes {
+		if route == s.node {
+			return s.node
+		}
+	}
+	return ""
+}
+
+// update binds session to node after successful call and unbinds after node failure
+func (s *Session) update(node string, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if err == nil {
+		s.node = node
+		return
+	}
+	// application errors not mean node unavailable
+	if verr, ok := err.(*errors.Error); ok && verr.Code < 500 {
+		return
+	}
+	if s.node == node {
+		s.node = ""
+	}
+}
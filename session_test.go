@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/selector"
+)
+
+// roundRobinSelector returns next route on each call across selects
+type roundRobinSelector struct {
+	idx int
+	sync.Mutex
+}
+
+func (s *roundRobinSelector) Select(routes []string, opts ...selector.SelectOption) (selector.Next, error) {
+	return func() string {
+		s.Lock()
+		defer s.Unlock()
+		route := routes[s.idx%len(routes)]
+		s.idx++
+		return route
+	}, nil
+}
+
+func (s *roundRobinSelector) Record(route string, err error) error {
+	return nil
+}
+
+func (s *roundRobinSelector) Reset() error {
+	return nil
+}
+
+func (s *roundRobinSelector) String() string {
+	return "roundrobin"
+}
+
+func TestSession(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"` + name + `"}`))
+		})
+	}
+	node1 := httptest.NewServer(handler("node1"))
+	defer node1.Close()
+	node2 := httptest.NewServer(handler("node2"))
+	defer node2.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(&roundRobinSelector{}))
+	addrs := client.WithAddress(node2.URL, node1.URL)
+
+	session := NewSession()
+	for i := 0; i < 3; i++ {
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, addrs, WithSession(session)); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != "node2" {
+			t.Fatalf("call %d: session affinity broken, got %s", i, rsp.Name)
+		}
+	}
+	if session.Node() != node2.URL {
+		t.Fatalf("invalid session node %s", session.Node())
+	}
+
+	// session node unavailable, fallback to other node
+	node2.Close()
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, addrs, WithSession(session),
+		client.WithRetries(1), client.WithRetry(client.RetryAlways)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "node1" || session.Node() != node1.URL {
+		t.Fatalf("session not moved to available node, got %s %s", rsp.Name, session.Node())
+	}
+}
+
+func TestSelectedNode(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ok.Close()
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fail.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.Selector(&roundRobinSelector{}))
+	req := c.NewRequest("test", "/test", &Request{})
+
+	var node string
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(fail.URL, ok.URL),
+		client.WithRetries(1), client.WithRetry(client.RetryAlways), WithSelectedNode(&node)); err != nil {
+		t.Fatal(err)
+	}
+	if node != ok.URL {
+		t.Fatalf("expected node %s, got %s", ok.URL, node)
+	}
+
+	if err := c.Call(context.TODO(), req, &Request{}, client.WithAddress(fail.URL), WithSelectedNode(&node)); err == nil {
+		t.Fatal("expected error")
+	}
+	if node != fail.URL {
+		t.Fatalf("expected node %s, got %s", fail.URL, node)
+	}
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+)
+
+// RequestSigner signs request before it sent, for example by setting signature headers,
+// body contains final request body bytes and nil for streamed body
+type RequestSigner interface {
+	Sign(hreq *http.Request, body []byte) error
+}
+
+// signRequest passes request with body bytes to signer
+func signRequest(signer RequestSigner, hreq *http.Request) error {
+	var body []byte
+	if hreq.GetBody != nil {
+		rc, err := hreq.GetBody()
+		if err != nil {
+			return err
+		}
+		body, err = ioutil.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return signer.Sign(hreq, body)
+}
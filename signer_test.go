@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+type hmacSigner struct {
+	key []byte
+}
+
+func (s *hmacSigner) signature(method, path, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(method + "\n" + path + "\n" + ts + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *hmacSigner) Sign(hreq *http.Request, body []byte) error {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	hreq.Header.Set("X-Timestamp", ts)
+	hreq.Header.Set("X-Signature", s.signature(hreq.Method, hreq.URL.Path, ts, body))
+	return nil
+}
+
+func TestRequestSigner(t *testing.T) {
+	signer := &hmacSigner{key: []byte("secret")}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) == 0 || r.Header.Get("X-Signature") != signer.signature(r.Method, r.URL.Path, r.Header.Get("X-Timestamp"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithRequestSigner(signer))
+
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{Name: "test"}), &Request{}, client.WithAddress(ts.URL), Method(http.MethodPost)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.unistack.org/micro/v3/metadata"
+)
+
+// flightCall is in-flight request, waiters get copy of its response
+type flightCall struct {
+	done chan struct{}
+	buf  []byte
+	err  error
+	dups int
+	// canceled true if leader request context done, waiters send own request
+	canceled bool
+}
+
+// flightGroup coalesces concurrent identical requests
+type flightGroup struct {
+	calls map[string]*flightCall
+	key   func(*http.Request) string
+	mu    sync.Mutex
+}
+
+// flightSkipHeaders has values unique for each call, like timeout and trace ids
+var flightSkipHeaders = []string{metadata.HeaderTimeout, "Traceparent", "Tracestate"}
+
+// newFlightGroup returns group with key func, default key skips per call headers and skip ones
+func newFlightGroup(key func(*http.Request) string, skip ...string) *flightGroup {
+	if key == nil {
+		names := make(map[string]struct{}, len(flightSkipHeaders)+len(skip))
+		for _, name := range append(flightSkipHeaders, skip...) {
+			if name != "" {
+				names[http.CanonicalHeaderKey(name)] = struct{}{}
+			}
+		}
+		key = func(hreq *http.Request) string {
+			return flightKey(hreq, names)
+		}
+	}
+	return &flightGroup{calls: make(map[string]*flightCall), key: key}
+}
+
+// flightKey returns method, url and request headers except skipped, so requests with
+// different credentials, accepted content or metadata never share response
+func flightKey(hreq *http.Request, skip map[string]struct{}) string {
+	names := make([]string, 0, len(hreq.Header))
+	for k := range hreq.Header {
+		if _, ok := skip[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(hreq.Method + " " + hreq.URL.String() + "\n" + hreq.Host)
+	for _, k := range names {
+		for _, v := range hreq.Header[k] {
+			b.WriteString("\n" + k + ": " + v)
+		}
+	}
+	return b.String()
+}
+
+// flightTransport sends single request for concurrent GET and HEAD requests with same key,
+// response buffered and shared only if there are waiters
+type flightTransport struct {
+	rt    http.RoundTripper
+	group *flightGroup
+}
+
+func (t *flightTransport) RoundTrip(hreq *http.Request) (*http.Response, error) {
+	if (hreq.Method != http.MethodGet && hreq.Method != http.MethodHead) || (hreq.Body != nil && hreq.Body != http.NoBody) {
+		return t.rt.RoundTrip(hreq)
+	}
+
+	g := t.group
+	key := g.key(hreq)
+	for {
+		g.mu.Lock()
+		c, ok := g.calls[key]
+		if !ok {
+			break
+		}
+		c.dups++
+		g.mu.Unlock()
+		select {
+		case <-hreq.Context().Done():
+			return nil, hreq.Context().Err()
+		case <-c.done:
+		}
+		if c.canceled {
+			// leader gave up, its context error not related to this request
+			continue
+		}
+		if c.err != nil {
+			return nil, c.err
+		}
+		return http.ReadResponse(bufio.NewReader(bytes.NewReader(c.buf)), hreq)
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	hrsp, err := t.rt.RoundTrip(hreq)
+
+	g.mu.Lock()
+	// requests started after this point sent again
+	delete(g.calls, key)
+	dups := c.dups
+	g.mu.Unlock()
+
+	if err == nil && dups > 0 {
+		// body replaced by buffered copy, so response still readable by leader
+		c.buf, err = httputil.DumpResponse(hrsp, true)
+		if err != nil {
+			_ = hrsp.Body.Close()
+			hrsp = nil
+		}
+	}
+	c.err = err
+	c.canceled = err != nil && hreq.Context().Err() != nil
+	close(c.done)
+
+	return hrsp, err
+}
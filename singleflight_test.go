@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestSingleFlight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Method == http.MethodGet {
+			<-release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"shared"}`))
+	}))
+	defer ts.Close()
+
+	// per call timeout, deadline and request id headers not prevent coalescing
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), SingleFlight(true),
+		RequestIDHeader("X-Request-Id"), DeadlineHeader("", DeadlineRFC3339))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+			rsp := &Request{}
+			req := c.NewRequest("test", "/test", &Request{}, WithMethod(http.MethodGet))
+			if err := c.Call(ctx, req, rsp, client.WithAddress(ts.URL)); err != nil {
+				errs <- err
+			} else if rsp.Name != "shared" {
+				errs <- fmt.Errorf("invalid response %s", rsp.Name)
+			}
+		}()
+	}
+	// let all calls join in-flight request
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	// mutating requests never coalesced
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 2; i++ {
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestSingleFlightKeyAndCancel(t *testing.T) {
+	var calls int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	ft := &flightTransport{group: newFlightGroup(nil), rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-release:
+		}
+		rec := httptest.NewRecorder()
+		_, _ = rec.WriteString(r.Header.Get("Accept"))
+		return rec.Result(), nil
+	})}
+
+	send := func(ctx context.Context, accept string) (string, error) {
+		hreq, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://test/test", nil)
+		hreq.Header.Set("Accept", accept)
+		hrsp, err := ft.RoundTrip(hreq)
+		if err != nil {
+			return "", err
+		}
+		defer hrsp.Body.Close()
+		b, err := io.ReadAll(hrsp.Body)
+		return string(b), err
+	}
+
+	// leader canceled, waiter sends own request instead of getting leader error
+	ctx, cancel := context.WithCancel(context.TODO())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := send(ctx, "application/json")
+		leader <- err
+	}()
+	<-started
+	waiter := make(chan string, 1)
+	go func() {
+		rsp, err := send(context.TODO(), "application/json")
+		if err != nil {
+			rsp = err.Error()
+		}
+		waiter <- rsp
+	}()
+
+	// request with other headers not coalesced
+	other := make(chan string, 1)
+	go func() {
+		rsp, _ := send(context.TODO(), "application/xml")
+		other <- rsp
+	}()
+	<-started
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-leader; err != context.Canceled {
+		t.Fatalf("leader must be canceled, got %v", err)
+	}
+	<-started
+	close(release)
+	if rsp := <-waiter; rsp != "application/json" {
+		t.Fatalf("waiter must send own request, got %s", rsp)
+	}
+	if rsp := <-other; rsp != "application/xml" {
+		t.Fatalf("request with other headers got %s", rsp)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected 3 requests, got %d", n)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.unistack.org/micro/v3/client"
 	"go.unistack.org/micro/v3/codec"
@@ -20,6 +22,7 @@ type httpStream struct {
 	conn    net.Conn
 	cf      codec.Codec
 	context context.Context
+	client  *httpClient
 	request client.Request
 	closed  chan bool
 	reader  *bufio.Reader
@@ -27,6 +30,14 @@ type httpStream struct {
 	ct      string
 	opts    client.CallOptions
 	sync.RWMutex
+	// sendClosed set by CloseSend
+	sendClosed int32
+	// idle limits wait of single Send or Recv
+	idle time.Duration
+}
+
+type closeWriter interface {
+	CloseWrite() error
 }
 
 var errShutdown = fmt.Errorf("connection is shut down")
@@ -65,12 +76,24 @@ func (h *httpStream) Send(msg interface{}) error {
 		return errShutdown
 	}
 
-	hreq, err := newRequest(h.context, h.address, h.request, h.ct, h.cf, msg, h.opts)
+	if atomic.LoadInt32(&h.sendClosed) == 1 {
+		return errors.BadRequest("go.micro.client", "send on closed stream")
+	}
+
+	hreq, err := h.client.newRequest(h.context, h.address, h.request, h.ct, h.cf, msg, h.opts)
 	if err != nil {
 		return err
 	}
 
-	return hreq.Write(h.conn)
+	if err = h.conn.SetWriteDeadline(h.deadline()); err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	if err = hreq.Write(h.conn); err != nil {
+		return h.connError(err)
+	}
+
+	return nil
 }
 
 func (h *httpStream) RecvMsg(msg interface{}) error {
@@ -86,22 +109,50 @@ func (h *httpStream) Recv(msg interface{}) error {
 		return errShutdown
 	}
 
+	if err := h.conn.SetReadDeadline(h.deadline()); err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
 	hrsp, err := http.ReadResponse(h.reader, new(http.Request))
 	if err != nil {
-		return errors.InternalServerError("go.micro.client", err.Error())
+		return h.connError(err)
 	}
 	defer hrsp.Body.Close()
 
 	return h.parseRsp(h.context, hrsp, h.cf, msg, h.opts)
 }
 
+// deadline returns connection deadline for next message, zero time means no deadline
+func (h *httpStream) deadline() time.Time {
+	if h.idle <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(h.idle)
+}
+
+// connError converts connection error, timeout means that server was silent longer than idle timeout
+func (h *httpStream) connError(err error) error {
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return errors.New("go.micro.client", fmt.Sprintf("stream idle timeout %v exceeded", h.idle), 408)
+	}
+	return errors.InternalServerError("go.micro.client", err.Error())
+}
+
 func (h *httpStream) Error() error {
 	h.RLock()
 	defer h.RUnlock()
 	return h.err
 }
 
+// CloseSend closes write half of connection, so server gets end of client messages,
+// responses still can be received, if connection not supports half-close it fully closed
 func (h *httpStream) CloseSend() error {
+	if !atomic.CompareAndSwapInt32(&h.sendClosed, 0, 1) || h.isClosed() {
+		return nil
+	}
+	if cw, ok := h.conn.(closeWriter); ok {
+		return cw.CloseWrite()
+	}
 	return h.Close()
 }
 
@@ -127,6 +178,10 @@ func (h *httpStream) parseRsp(ctx context.Context, hrsp *http.Response, cf codec
 			return nil
 		}
 
+		if ok, derr := decodeError(h.request.Service(), hrsp, opts); ok {
+			return derr
+		}
+
 		if hrsp.StatusCode < 400 {
 			if err = cf.ReadBody(hrsp.Body, rsp); err != nil {
 				return errors.InternalServerError("go.micro.client", err.Error())
@@ -145,7 +200,7 @@ func (h *httpStream) parseRsp(ctx context.Context, hrsp *http.Response, cf codec
 			if cerr != nil {
 				return errors.InternalServerError("go.micro.client", cerr.Error())
 			}
-			return errors.New("go.micro.client", string(buf), int32(hrsp.StatusCode))
+			return responseError(h.request.Service(), hrsp.StatusCode, buf)
 		}
 
 		if cerr := cf.ReadBody(hrsp.Body, err); cerr != nil {
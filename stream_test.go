@@ -0,0 +1,151 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+func TestStreamCloseSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		var names []string
+		// read client messages until half-close
+		for {
+			hreq, err := http.ReadRequest(br)
+			if err != nil {
+				break
+			}
+			req := &Request{}
+			_ = json.NewDecoder(hreq.Body).Decode(req)
+			names = append(names, req.Name)
+		}
+		body := fmt.Sprintf(`{"name":%q}`, strings.Join(names, ","))
+		_, _ = fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	}()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+	s, err := c.Stream(context.TODO(), c.NewRequest("test", "/test", &Request{}), client.WithAddress(ln.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, name := range []string{"a", "b"} {
+		if err = s.Send(&Request{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Send(&Request{Name: "c"}); err == nil {
+		t.Fatal("send after CloseSend must fail")
+	}
+
+	rsp := &Request{}
+	if err = s.Recv(rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "a,b" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Recv(rsp); err == nil {
+		t.Fatal("recv after Close must fail")
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				// respond to each message after delay
+				for {
+					hreq, err := http.ReadRequest(br)
+					if err != nil {
+						return
+					}
+					req := &Request{}
+					_ = json.NewDecoder(hreq.Body).Decode(req)
+					if req.Name == "silent" {
+						continue
+					}
+					time.Sleep(100 * time.Millisecond)
+					body := fmt.Sprintf(`{"name":%q}`, req.Name)
+					_, _ = fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+				}
+			}()
+		}
+	}()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), client.RequestTimeout(50*time.Millisecond))
+
+	s, err := c.Stream(context.TODO(), c.NewRequest("test", "/test", &Request{}), client.WithAddress(ln.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Send(&Request{Name: "silent"}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.Recv(&Request{})
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 408 {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	s.Close()
+
+	// periodic messages slower than request timeout are kept by idle timeout
+	s, err = c.Stream(context.TODO(), c.NewRequest("test", "/test", &Request{}), client.WithAddress(ln.Addr().String()), WithStreamIdleTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		if err = s.Send(&Request{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+		rsp := &Request{}
+		if err = s.Recv(rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Name != name {
+			t.Fatalf("invalid response %#+v", rsp)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"go.unistack.org/micro/v3/client"
+)
+
+// TestAddress is the static address used by client created with NewTestClient
+const TestAddress = "http://test.local"
+
+// handlerTransport serves requests by handler in memory
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(hreq *http.Request) (*http.Response, error) {
+	// handler sees request like server one
+	sreq := hreq.Clone(hreq.Context())
+	sreq.RequestURI = hreq.URL.RequestURI()
+	if sreq.Body == nil {
+		sreq.Body = http.NoBody
+	}
+
+	w := &responseBuffer{header: make(http.Header)}
+	t.handler.ServeHTTP(w, sreq)
+	if hreq.Body != nil {
+		_ = hreq.Body.Close()
+	}
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(w.status),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.sent,
+		Body:          ioutil.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Request:       hreq,
+	}, nil
+}
+
+// responseBuffer is http.ResponseWriter that keeps response in memory
+type responseBuffer struct {
+	header http.Header
+	sent   http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *responseBuffer) Header() http.Header {
+	return w.header
+}
+
+func (w *responseBuffer) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(p)
+}
+
+func (w *responseBuffer) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		// later header changes not visible to client like in real server
+		w.sent = w.header.Clone()
+	}
+}
+
+// NewTestClient returns client that sends all requests to handler in memory without network,
+// router and selector not used, all calls go to TestAddress, Stream not supported
+func NewTestClient(handler http.Handler, opts ...client.Option) client.Client {
+	opts = append([]client.Option{
+		HTTPClient(&http.Client{Transport: &handlerTransport{handler: handler}}),
+		client.Lookup(func(context.Context, client.Request, client.CallOptions) ([]string, error) {
+			return []string{TestAddress}, nil
+		}),
+	}, opts...)
+	return NewClient(opts...)
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+	"go.unistack.org/micro/v3/errors"
+)
+
+func TestNewTestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/users/", func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"name":%q,"field1":%q}`, r.Method+" "+r.RequestURI, buf)
+	})
+
+	c := NewTestClient(mux, client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Request{}
+	if err := c.Call(context.TODO(), c.NewRequest("users", "/v1/users/{name}", &Request{Name: "test", Field1: "field1"}), rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "POST /v1/users/test" || !strings.HasPrefix(rsp.Field1, `{"name":"","field1":"field1"`) {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+
+	err := c.Call(context.TODO(), c.NewRequest("users", "/v1/unknown", &Request{}), rsp)
+	if verr := errors.FromError(err); err == nil || verr.Code != http.StatusNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if _, err = c.Stream(context.TODO(), c.NewRequest("users", "/v1/users/test", &Request{})); err == nil {
+		t.Fatal("stream must fail without network transport")
+	}
+}
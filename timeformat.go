@@ -0,0 +1,280 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// formatTimes converts time.Time fields of json encoded message from RFC3339 to layout
+func formatTimes(buf []byte, t reflect.Type, layout string) ([]byte, error) {
+	return convertTimes(buf, t, func(s string) (string, error) {
+		v, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return "", err
+		}
+		return v.Format(layout), nil
+	})
+}
+
+// parseTimes converts time.Time fields of json encoded message from layout to RFC3339
+func parseTimes(buf []byte, t reflect.Type, layout string) ([]byte, error) {
+	return convertTimes(buf, t, func(s string) (string, error) {
+		v, err := time.Parse(layout, s)
+		if err != nil {
+			return "", err
+		}
+		return v.Format(time.RFC3339Nano), nil
+	})
+}
+
+// convertTimes replaces time values of buf in place, key order, spacing and escaping
+// of other values kept as is
+func convertTimes(buf []byte, t reflect.Type, fn func(string) (string, error)) ([]byte, error) {
+	if len(bytes.TrimSpace(buf)) == 0 || t == nil {
+		return buf, nil
+	}
+
+	w := &timeWalker{buf: buf, fn: fn}
+	if err := w.value(t); err != nil {
+		return nil, err
+	}
+	if w.skipSpace(); w.pos != len(buf) {
+		return nil, fmt.Errorf("invalid character %q after top-level value", buf[w.pos])
+	}
+	if len(w.edits) == 0 {
+		return buf, nil
+	}
+
+	out := make([]byte, 0, len(buf))
+	last := 0
+	for _, e := range w.edits {
+		out = append(out, buf[last:e.start]...)
+		out = append(out, e.val...)
+		last = e.end
+	}
+	return append(out, buf[last:]...), nil
+}
+
+// timeEdit replaces buf[start:end] with val
+type timeEdit struct {
+	val        []byte
+	start, end int
+}
+
+// timeWalker scans json value according to type and collects replacements of time values
+type timeWalker struct {
+	fn    func(string) (string, error)
+	buf   []byte
+	edits []timeEdit
+	pos   int
+}
+
+func (w *timeWalker) skipSpace() {
+	for w.pos < len(w.buf) {
+		switch w.buf[w.pos] {
+		case ' ', '\t', '\r', '\n':
+			w.pos++
+		default:
+			return
+		}
+	}
+}
+
+// expect consumes c after optional spaces
+func (w *timeWalker) expect(c byte) error {
+	if w.skipSpace(); w.pos >= len(w.buf) {
+		return fmt.Errorf("unexpected end of JSON input")
+	}
+	if w.buf[w.pos] != c {
+		return fmt.Errorf("invalid character %q, expected %q", w.buf[w.pos], c)
+	}
+	w.pos++
+	return nil
+}
+
+// str returns end of json string starting at pos
+func (w *timeWalker) str() (int, error) {
+	for i := w.pos + 1; i < len(w.buf); i++ {
+		switch w.buf[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unexpected end of JSON input")
+}
+
+// value walks value at pos, t is nil for values without time fields
+func (w *timeWalker) value(t reflect.Type) error {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if w.skipSpace(); w.pos >= len(w.buf) {
+		return fmt.Errorf("unexpected end of JSON input")
+	}
+
+	switch w.buf[w.pos] {
+	case '"':
+		end, err := w.str()
+		if err != nil {
+			return err
+		}
+		if t == timeType {
+			var s string
+			if err = json.Unmarshal(w.buf[w.pos:end], &s); err != nil {
+				return err
+			}
+			if s, err = w.fn(s); err != nil {
+				return err
+			}
+			val, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			w.edits = append(w.edits, timeEdit{start: w.pos, end: end, val: val})
+		}
+		w.pos = end
+	case '{':
+		return w.object(t)
+	case '[':
+		var et reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			et = t.Elem()
+		}
+		w.pos++
+		if w.skipSpace(); w.pos < len(w.buf) && w.buf[w.pos] == ']' {
+			w.pos++
+			return nil
+		}
+		for {
+			if err := w.value(et); err != nil {
+				return err
+			}
+			if w.skipSpace(); w.pos < len(w.buf) && w.buf[w.pos] == ']' {
+				w.pos++
+				return nil
+			}
+			if err := w.expect(','); err != nil {
+				return err
+			}
+		}
+	default:
+		// number, bool or null
+		start := w.pos
+		for w.pos < len(w.buf) && !strings.ContainsRune(" \t\r\n,]}", rune(w.buf[w.pos])) {
+			w.pos++
+		}
+		if w.pos == start {
+			return fmt.Errorf("invalid character %q looking for beginning of value", w.buf[start])
+		}
+	}
+	return nil
+}
+
+func (w *timeWalker) object(t reflect.Type) error {
+	var fields map[string]reflect.Type
+	var elem reflect.Type
+	if t != nil {
+		switch t.Kind() {
+		case reflect.Struct:
+			fields = make(map[string]reflect.Type)
+			jsonFields(t, fields)
+		case reflect.Map:
+			elem = t.Elem()
+		}
+	}
+
+	w.pos++
+	if w.skipSpace(); w.pos < len(w.buf) && w.buf[w.pos] == '}' {
+		w.pos++
+		return nil
+	}
+	for {
+		if w.skipSpace(); w.pos >= len(w.buf) || w.buf[w.pos] != '"' {
+			return fmt.Errorf("invalid object key")
+		}
+		end, err := w.str()
+		if err != nil {
+			return err
+		}
+		var key string
+		if err = json.Unmarshal(w.buf[w.pos:end], &key); err != nil {
+			return err
+		}
+		w.pos = end
+		if err = w.expect(':'); err != nil {
+			return err
+		}
+
+		ft := elem
+		if fields != nil {
+			ft = fieldType(fields, key)
+		}
+		if err = w.value(ft); err != nil {
+			return err
+		}
+
+		if w.skipSpace(); w.pos < len(w.buf) && w.buf[w.pos] == '}' {
+			w.pos++
+			return nil
+		}
+		if err = w.expect(','); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonFields collects json names of exported struct fields, fields of embedded
+// structs without json tag promoted unless shadowed
+func jsonFields(t reflect.Type, fields map[string]reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if fld.PkgPath != "" {
+			continue
+		}
+		name := fld.Name
+		if tag, ok := fld.Tag.Lookup("json"); ok {
+			if tag = strings.Split(tag, ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+		} else if fld.Anonymous {
+			ft := fld.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = fld.Type
+		}
+	}
+	for _, et := range embedded {
+		jsonFields(et, fields)
+	}
+}
+
+// fieldType returns type of field with json name key, case insensitive like encoding/json
+func fieldType(fields map[string]reflect.Type, key string) reflect.Type {
+	if t, ok := fields[key]; ok {
+		return t
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t
+		}
+	}
+	return nil
+}
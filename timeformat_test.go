@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestTimeFormat(t *testing.T) {
+	type Event struct {
+		At   time.Time   `json:"at"`
+		Next *time.Time  `json:"next,omitempty"`
+		All  []time.Time `json:"all"`
+		Name string      `json:"name"`
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(buf), `"at":"02.01.2024 10:30"`) || !strings.Contains(string(buf), `"all":["03.01.2024 11:00"]`) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write(buf)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"at":"05.02.2024 12:15","next":"06.02.2024 13:00","all":["07.02.2024 14:45"],"name":"event"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	layout := "02.01.2006 15:04"
+	at := time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC)
+	req := c.NewRequest("test", "/test", &Event{At: at, All: []time.Time{at.Add(24*time.Hour + 30*time.Minute)}, Name: "event"})
+
+	rsp := &Event{}
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), WithTimeFormat(layout)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.At.Format(layout) != "05.02.2024 12:15" || rsp.Next == nil || rsp.Next.Format(layout) != "06.02.2024 13:00" ||
+		len(rsp.All) != 1 || rsp.All[0].Format(layout) != "07.02.2024 14:45" || rsp.Name != "event" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+func TestTimeFormatInPlace(t *testing.T) {
+	type Event struct {
+		At   time.Time `json:"at"`
+		Name string    `json:"name"`
+	}
+
+	// key order, spacing and escaping of other values kept
+	buf := []byte(`{"name": "<a&b>", "z":1, "at": "2024-01-02T10:30:00Z" ,"a":[1,{"at":"x"}]}`)
+	out, err := formatTimes(buf, reflect.TypeOf(&Event{}), "02.01.2006 15:04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"name": "<a&b>", "z":1, "at": "02.01.2024 10:30" ,"a":[1,{"at":"x"}]}`; string(out) != exp {
+		t.Fatalf("expected %s, got %s", exp, out)
+	}
+
+	// not json content type sent as is
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(buf), `"at":"2024-01-02T10:30:00Z"`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/x-test", codec.NewCodec()), client.ContentType("application/x-test"))
+	req := c.NewRequest("test", "/test", &Event{At: time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC)})
+	if err = c.Call(context.TODO(), req, &Event{}, client.WithAddress(ts.URL), WithTimeFormat("02.01.2006 15:04")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.unistack.org/micro/v3/errors"
+)
+
+// ErrConnAcquireTimeout returned when connection not acquired in time passed via WithConnAcquireTimeout
+var ErrConnAcquireTimeout = errors.New("go.micro.client", "connection acquire timeout", 408)
+
+// acquireTimer cancels request if connection is not acquired in time
+type acquireTimer struct {
+	cancel context.CancelFunc
+	timer  *time.Timer
+	d      time.Duration
+	fired  int32
+}
+
+func newAcquireTimer(hreq *http.Request, d time.Duration) (*http.Request, *acquireTimer) {
+	ctx, cancel := context.WithCancel(hreq.Context())
+	t := &acquireTimer{cancel: cancel, d: d}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.stop()
+			t.timer = time.AfterFunc(t.d, func() {
+				atomic.StoreInt32(&t.fired, 1)
+				t.cancel()
+			})
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			t.stop()
+		},
+	}
+	return hreq.WithContext(httptrace.WithClientTrace(ctx, trace)), t
+}
+
+func (t *acquireTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// Fired returns true if request canceled by timer
+func (t *acquireTimer) Fired() bool {
+	return atomic.LoadInt32(&t.fired) == 1
+}
+
+// Close stops timer and release context resources
+func (t *acquireTimer) Close() {
+	t.stop()
+	t.cancel()
+}
+
+// ErrResponseHeaderTimeout returned when response headers not received in time passed via WithResponseHeaderTimeout
+var ErrResponseHeaderTimeout = errors.New("go.micro.client", "response header timeout", 408)
+
+// headerTimer cancels request if response headers not received in time after request written,
+// body read is not limited by it
+type headerTimer struct {
+	cancel context.CancelFunc
+	timer  *time.Timer
+	d      time.Duration
+	fired  int32
+	// stopped set when headers received, so timer not started
+	stopped bool
+	sync.Mutex
+}
+
+func newHeaderTimer(hreq *http.Request, d time.Duration) (*http.Request, *headerTimer) {
+	ctx, cancel := context.WithCancel(hreq.Context())
+	t := &headerTimer{cancel: cancel, d: d}
+	trace := &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.Lock()
+			if t.timer == nil && !t.stopped {
+				t.timer = time.AfterFunc(t.d, func() {
+					atomic.StoreInt32(&t.fired, 1)
+					t.cancel()
+				})
+			}
+			t.Unlock()
+		},
+		GotFirstResponseByte: t.stop,
+	}
+	return hreq.WithContext(httptrace.WithClientTrace(ctx, trace)), t
+}
+
+func (t *headerTimer) stop() {
+	t.Lock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.Unlock()
+}
+
+// Fired returns true if request canceled by timer
+func (t *headerTimer) Fired() bool {
+	return atomic.LoadInt32(&t.fired) == 1
+}
+
+// Close stops timer and release context resources
+func (t *headerTimer) Close() {
+	t.stop()
+	t.cancel()
+}
+
+// ClientStats holds connection statistics accumulated across calls
+type ClientStats struct {
+	// Dialed number of new connections
+	Dialed uint64
+	// Reused number of reused connections
+	Reused uint64
+}
+
+// connStats collects connection statistics via httptrace
+type connStats struct {
+	dialed uint64
+	reused uint64
+}
+
+func (s *connStats) trace(hreq *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				atomic.AddUint64(&s.dialed, 1)
+				return
+			}
+			atomic.AddUint64(&s.reused, 1)
+		},
+	}
+	return hreq.WithContext(httptrace.WithClientTrace(hreq.Context(), trace))
+}
+
+func (s *connStats) stats() ClientStats {
+	return ClientStats{
+		Dialed: atomic.LoadUint64(&s.dialed),
+		Reused: atomic.LoadUint64(&s.reused),
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync"
+	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestConnAcquireTimeout(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-block
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	c := NewClient(
+		client.Codec("application/json", codec.NewCodec()),
+		HTTPClient(&http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}}),
+	)
+
+	go func() {
+		rsp := make(map[string]interface{})
+		_ = c.Call(context.TODO(), c.NewRequest("test", "/slow", &Request{}), &rsp, client.WithAddress(ts.URL))
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	rsp := make(map[string]interface{})
+	err := c.Call(context.TODO(), c.NewRequest("test", "/fast", &Request{}), &rsp,
+		client.WithAddress(ts.URL),
+		WithConnAcquireTimeout(50*time.Millisecond),
+	)
+	if err != ErrConnAcquireTimeout {
+		t.Fatalf("expected acquire timeout, got %v", err)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// body slower than header timeout
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"name":"slow body"}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	err := c.Call(context.TODO(), c.NewRequest("test", "/slow-headers", &Request{}), &Request{},
+		client.WithAddress(ts.URL), WithResponseHeaderTimeout(50*time.Millisecond))
+	if err != ErrResponseHeaderTimeout {
+		t.Fatalf("expected response header timeout, got %v", err)
+	}
+
+	rsp := &Request{}
+	if err = c.Call(context.TODO(), c.NewRequest("test", "/slow-body", &Request{}), rsp,
+		client.WithAddress(ts.URL), WithResponseHeaderTimeout(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "slow body" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+	sc, ok := c.(interface{ Stats() ClientStats })
+	if !ok {
+		t.Fatal("client does not provide stats")
+	}
+
+	for i := 0; i < 3; i++ {
+		rsp := &Request{}
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), rsp, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := sc.Stats()
+	if stats.Dialed != 1 || stats.Reused != 2 {
+		t.Fatalf("invalid stats %#+v", stats)
+	}
+}
+
+func TestClientTrace(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var reused []bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			reused = append(reused, info.Reused)
+			mu.Unlock()
+		},
+	}
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()), WithClientTrace(trace))
+
+	for i := 0; i < 2; i++ {
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}), &Request{}, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reused) != 2 || reused[0] || !reused[1] {
+		t.Fatalf("invalid connection reuse %v", reused)
+	}
+}
+
+func TestRequestClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	for i := 0; i < 2; i++ {
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/test", &Request{}, WithClose(true)), &Request{}, client.WithAddress(ts.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s := c.(*httpClient).Stats(); s.Dialed != 2 || s.Reused != 0 {
+		t.Fatalf("connections must not be pooled, got %#+v", s)
+	}
+}
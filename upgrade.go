@@ -0,0 +1,40 @@
+package http
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+)
+
+// Upgraded holds connection switched to other protocol after 101 Switching Protocols response
+type Upgraded struct {
+	// Conn underlying connection, used to write
+	Conn net.Conn
+	// Reader must be used to read from connection, it may contain already buffered data
+	Reader *bufio.Reader
+	// Response with headers of 101 Switching Protocols response
+	Response *http.Response
+}
+
+// Close closes upgraded connection
+func (u *Upgraded) Close() error {
+	return u.Response.Body.Close()
+}
+
+type upgrade struct {
+	upgraded *Upgraded
+	protocol string
+}
+
+// trace remembers connection used by request
+func (u upgrade) trace(hreq *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			u.upgraded.Conn = info.Conn
+		},
+	}
+	hreq.Header.Set("Connection", "Upgrade")
+	hreq.Header.Set("Upgrade", u.protocol)
+	return hreq.WithContext(httptrace.WithClientTrace(hreq.Context(), trace))
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
+)
+
+func TestUpgrade(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\nhello\n")
+		_ = brw.Flush()
+		line, err := brw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		_, _ = brw.WriteString("echo " + line)
+		_ = brw.Flush()
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	u := &Upgraded{}
+	if err := c.Call(context.TODO(), c.NewRequest("test", "/test", nil), nil, client.WithAddress(ts.URL), Method(http.MethodGet), WithUpgrade("echo", u)); err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+
+	if u.Conn == nil || u.Reader == nil || u.Response.Header.Get("Upgrade") != "echo" {
+		t.Fatalf("connection not upgraded %#+v", u)
+	}
+
+	line, err := u.Reader.ReadString('\n')
+	if err != nil || line != "hello\n" {
+		t.Fatalf("invalid buffered data %q %v", line, err)
+	}
+
+	if _, err = u.Conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	if line, err = u.Reader.ReadString('\n'); err != nil || line != "echo ping\n" {
+		t.Fatalf("invalid echo %q %v", line, err)
+	}
+}
@@ -1,18 +1,30 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
 	"go.unistack.org/micro/v3/errors"
 	"go.unistack.org/micro/v3/logger"
+	"go.unistack.org/micro/v3/metadata"
 	rutil "go.unistack.org/micro/v3/util/reflect"
 )
 
@@ -38,6 +50,99 @@ func GetError(err error) interface{} {
 	return err
 }
 
+// newUUID returns random (version 4) uuid string
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// releaseBody calls release funcs after response body closed
+type releaseBody struct {
+	io.ReadCloser
+	release []func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		for _, fn := range b.release {
+			fn()
+		}
+	})
+	return err
+}
+
+// breakerEndpoint returns circuit breaker endpoint name for request
+func breakerEndpoint(req client.Request) string {
+	ep := req.Endpoint()
+	if !strings.HasPrefix(ep, "/") {
+		ep = "/" + ep
+	}
+	return req.Service() + ep
+}
+
+// isArrayRoot checks that buffered body starts with array
+func isArrayRoot(br *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		buf, err := br.Peek(i)
+		if err != nil {
+			return false
+		}
+		switch buf[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// sliceFieldPointer returns pointer to named slice field of struct
+func sliceFieldPointer(src interface{}, name string) (interface{}, error) {
+	v := reflect.ValueOf(src)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("response must be non nil pointer to struct")
+	}
+	fld := v.Elem().FieldByName(name)
+	if !fld.IsValid() || fld.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("response field %s not found or not a slice", name)
+	}
+	return fld.Addr().Interface(), nil
+}
+
+// sleepContext waits for d or context done, returns 408 error if context done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
+	case <-t.C:
+		return nil
+	}
+}
+
+// stackDump returns stack traces of all goroutines, dump taken by timer goroutine
+// must include goroutines of blocked call
+func stackDump() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
 func newPathRequest(path string, method string, body string, msg interface{}, tags []string, parameters map[string]map[string]string) (string, interface{}, error) {
 	// parse via https://github.com/googleapis/googleapis/blob/master/google/api/http.proto definition
 	tpl, err := newTemplate(path)
@@ -45,8 +150,11 @@ func newPathRequest(path string, method string, body string, msg interface{}, ta
 		return "", nil, err
 	}
 
-	if len(tpl) > 0 && msg == nil {
-		return "", nil, fmt.Errorf("nil message but path params requested: %v", path)
+	if msg == nil {
+		if strings.ContainsRune(path, '{') {
+			return "", nil, fmt.Errorf("nil message but path params requested: %v", path)
+		}
+		return path, nil, nil
 	}
 
 	fieldsmapskip := make(map[string]struct{})
@@ -85,12 +193,13 @@ func newPathRequest(path string, method string, body string, msg interface{}, ta
 	}
 
 	values := url.Values{}
+	// GET, HEAD and OPTIONS requests have no body, all fields sent in query
+	noBody := noBodyMethod(method)
+	// named body field must be present in message and not empty
+	bodyFound := body == "" || body == "*" || noBody
 	// copy cycle
 	for i := 0; i < tmsg.NumField(); i++ {
 		val := tmsg.Field(i)
-		if val.IsZero() {
-			continue
-		}
 		fld := tmsg.Type().Field(i)
 		// Skip unexported fields.
 		if fld.PkgPath != "" {
@@ -101,29 +210,7 @@ func newPathRequest(path string, method string, body string, msg interface{}, ta
 			continue
 		}
 		*/
-		t := &tag{}
-		for _, tn := range tags {
-			ts, ok := fld.Tag.Lookup(tn)
-			if !ok {
-				continue
-			}
-
-			tp := strings.Split(ts, ",")
-			// special
-			switch tn {
-			case "protobuf": // special
-				for _, p := range tp {
-					if idx := strings.Index(p, "name="); idx > 0 {
-						t = &tag{key: tn, name: p[idx:]}
-					}
-				}
-			default:
-				t = &tag{key: tn, name: tp[0]}
-			}
-			if t.name != "" {
-				break
-			}
-		}
+		t := fieldTag(fld, tags)
 
 		cname := t.name
 		if cname == "" {
@@ -131,6 +218,9 @@ func newPathRequest(path string, method string, body string, msg interface{}, ta
 			// fallback to lowercase
 			t.name = strings.ToLower(fld.Name)
 		}
+		if t.name == body && !val.IsZero() {
+			bodyFound = true
+		}
 		if _, ok := parameters["header"][cname]; ok {
 			continue
 		}
@@ -153,21 +243,19 @@ func newPathRequest(path string, method string, body string, msg interface{}, ta
 			default:
 				fieldsmap[t.name] = getParam(val)
 			}
-		} else if (body == "*" || body == t.name) && method != http.MethodGet {
+		} else if (body == "*" || body == t.name) && !noBody {
 			if tnmsg.Field(i).CanSet() {
 				tnmsg.Field(i).Set(val)
 			}
 		} else {
-			if val.Type().Kind() == reflect.Slice {
-				for idx := 0; idx < val.Len(); idx++ {
-					values.Add(t.name, getParam(val.Index(idx)))
-				}
-			} else {
-				values.Add(t.name, getParam(val))
-			}
+			addQueryValues(values, t.name, val, tags)
 		}
 	}
 
+	if !bodyFound {
+		return "", nil, fmt.Errorf("body field %s not found or empty", body)
+	}
+
 	// check not filled stuff
 	for k, v := range fieldsmap {
 		_, ok := fieldsmapskip[k]
@@ -243,9 +331,85 @@ func newTemplate(path string) ([]string, error) {
 	return tpl, nil
 }
 
-func (h *httpClient) parseRsp(ctx context.Context, hrsp *http.Response, rsp interface{}, opts client.CallOptions) error {
+func (h *httpClient) parseRsp(ctx context.Context, req client.Request, hrsp *http.Response, cf codec.Codec, rsp interface{}, opts client.CallOptions) error {
 	var err error
 
+	// transport sets NoBody for HEAD response and zero Content-Length,
+	// OPTIONS response body is not a message, raw response can be used to read it
+	empty := hrsp.Body == nil || hrsp.Body == http.NoBody ||
+		(hrsp.Request != nil && (hrsp.Request.Method == http.MethodHead || hrsp.Request.Method == http.MethodOptions))
+	var body *trackedBody
+	if hrsp.Body != nil {
+		body = &trackedBody{ReadCloser: hrsp.Body}
+		hrsp.Body = body
+	}
+
+	if status, ok := opts.Context.Value(responseStatusKey{}).(*int); ok && status != nil {
+		*status = hrsp.StatusCode
+	}
+
+	// normalized status used for success and error decisions
+	if fn, ok := opts.Context.Value(statusRewriterKey{}).(func(int) int); ok && fn != nil {
+		hrsp.StatusCode = fn(hrsp.StatusCode)
+	}
+
+	maxSize := DefaultMaxRecvMsgSize
+	if v, ok := h.opts.Context.Value(maxRecvMsgSizeKey{}).(int); ok && v > 0 {
+		maxSize = v
+	}
+
+	// status from body envelope handled as http status, so error map and decoder applied to it
+	if fn, ok := opts.Context.Value(envelopeStatusKey{}).(EnvelopeStatus); ok && fn != nil && !empty && hrsp.StatusCode >= 200 && hrsp.StatusCode < 300 {
+		// whole body buffered, so it limited like grpc-web frames
+		buf, rerr := io.ReadAll(io.LimitReader(hrsp.Body, int64(maxSize)+1))
+		if rerr != nil {
+			return body.error(rerr)
+		}
+		if len(buf) > maxSize {
+			return errors.New("go.micro.client", fmt.Sprintf("envelope body size exceeds limit %d", maxSize), http.StatusBadGateway)
+		}
+		code, cerr := fn(buf)
+		if cerr != nil {
+			return errors.InternalServerError("go.micro.client", fmt.Sprintf("failed to read envelope status: %v", cerr))
+		}
+		if code >= 400 {
+			hrsp.StatusCode = code
+		}
+		hrsp.Body = io.NopCloser(bytes.NewReader(buf))
+	}
+
+	if md, ok := opts.Context.Value(responseMetadataKey{}).(*metadata.Metadata); ok && md != nil {
+		*md = metadata.New(len(hrsp.Header))
+		for k, v := range hrsp.Header {
+			md.Set(k, strings.Join(v, ", "))
+		}
+	}
+
+	// trailers-only response has status in headers and empty body
+	if v, ok := opts.Context.Value(grpcWebKey{}).(bool); ok && v && hrsp.StatusCode < 400 && hrsp.Body != nil {
+		if err = grpcWebDeframe(req.Service(), hrsp, maxSize); err != nil {
+			return err
+		}
+		empty = hrsp.Body == http.NoBody
+	}
+
+	if methods, ok := opts.Context.Value(allowedMethodsKey{}).(*[]string); ok && methods != nil {
+		*methods = nil
+		for _, v := range hrsp.Header.Values("Allow") {
+			for _, m := range strings.Split(v, ",") {
+				if m = strings.TrimSpace(m); m != "" {
+					*methods = append(*methods, m)
+				}
+			}
+		}
+	}
+
+	if fn, ok := opts.Context.Value(warningHandlerKey{}).(func([]string)); ok && fn != nil {
+		if warnings := hrsp.Header.Values("Warning"); len(warnings) > 0 {
+			fn(warnings)
+		}
+	}
+
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
@@ -254,32 +418,89 @@ func (h *httpClient) parseRsp(ctx context.Context, hrsp *http.Response, rsp inte
 		if hrsp.StatusCode == http.StatusNoContent {
 			return nil
 		}
-		ct := DefaultContentType
-
-		if htype := hrsp.Header.Get("Content-Type"); htype != "" {
-			ct = htype
+		// HEAD, OPTIONS response and empty body have nothing to unmarshal, headers available via ResponseMetadata
+		if hrsp.StatusCode < 400 && empty {
+			return nil
 		}
-
-		cf, cerr := h.newCodec(ct)
-		if hrsp.StatusCode >= 400 && cerr != nil {
-			var buf []byte
-			if hrsp.Body != nil {
-				buf, err = io.ReadAll(hrsp.Body)
-				if err != nil && h.opts.Logger.V(logger.ErrorLevel) {
-					h.opts.Logger.Errorf(ctx, "failed to read body: %v", err)
+		if ok, derr := decodeError(req.Service(), hrsp, opts); ok {
+			return derr
+		}
+		// content type of response body, request content type if not known
+		rct := req.ContentType()
+		if len(opts.ContentType) > 0 {
+			rct = opts.ContentType
+		}
+		// select codec by response content type, fallback to request codec
+		if htype := hrsp.Header.Get(metadata.HeaderContentType); htype != "" {
+			rcf, cerr := h.newCodec(htype)
+			switch {
+			case cerr == nil:
+				cf = rcf
+				rct = htype
+			case hrsp.StatusCode >= 400:
+				var buf []byte
+				if hrsp.Body != nil {
+					buf, err = io.ReadAll(hrsp.Body)
+					if err != nil && h.opts.Logger.V(logger.ErrorLevel) {
+						h.opts.Logger.Errorf(ctx, "failed to read body: %v", err)
+					}
 				}
+				// response like text/plain or something else, return original error
+				return responseError(req.Service(), hrsp.StatusCode, buf)
+			}
+		} else if act, ok := opts.Context.Value(assumeContentTypeKey{}).(string); ok && act != "" {
+			rcf, cerr := h.newCodec(act)
+			if cerr != nil {
+				return errors.InternalServerError("go.micro.client", cerr.Error())
 			}
-			// response like text/plain or something else, return original error
-			return errors.New("go.micro.client", string(buf), int32(hrsp.StatusCode))
-		} else if cerr != nil {
-			return errors.InternalServerError("go.micro.client", cerr.Error())
+			cf = rcf
+			rct = act
 		}
 
 		// succeseful response
 		if hrsp.StatusCode < 400 {
-			if err = cf.ReadBody(hrsp.Body, rsp); err != nil {
+			if w, ok := rsp.(io.Writer); ok {
+				if v, ok := opts.Context.Value(streamResponseKey{}).(bool); ok && v {
+					return streamResponse(ctx, w, hrsp.Body)
+				}
+			}
+			if rsp, err = allocResponse(rsp); err != nil {
 				return errors.InternalServerError("go.micro.client", err.Error())
 			}
+			// raw data requested
+			if frame, ok := rsp.(*codec.Frame); ok {
+				if frame.Data, err = io.ReadAll(hrsp.Body); err != nil {
+					return body.error(err)
+				}
+				return nil
+			}
+			if eh, ok := opts.Context.Value(elementHandlerKey{}).(elementHandler); ok {
+				return readElements(hrsp.Body, hrsp.Header.Get(metadata.HeaderContentType), cf, eh)
+			}
+
+			var rbody io.Reader = hrsp.Body
+			if name, ok := opts.Context.Value(arrayRootFieldKey{}).(string); ok && name != "" {
+				br := bufio.NewReader(hrsp.Body)
+				rbody = br
+				if isArrayRoot(br) {
+					if rsp, err = sliceFieldPointer(rsp, name); err != nil {
+						return errors.InternalServerError("go.micro.client", err.Error())
+					}
+				}
+			}
+			if layout, ok := opts.Context.Value(timeFormatKey{}).(string); ok && layout != "" && isJSON(rct) {
+				buf, rerr := io.ReadAll(rbody)
+				if rerr != nil {
+					return body.error(rerr)
+				}
+				if buf, err = parseTimes(buf, reflect.TypeOf(rsp), layout); err != nil {
+					return errors.InternalServerError("go.micro.client", err.Error())
+				}
+				rbody = bytes.NewReader(buf)
+			}
+			if err = cf.ReadBody(rbody, rsp); err != nil {
+				return body.error(err)
+			}
 			return nil
 		}
 
@@ -293,32 +514,146 @@ func (h *httpClient) parseRsp(ctx context.Context, hrsp *http.Response, rsp inte
 			}
 		}
 
-		if !ok || rerr == nil {
-			buf, rerr := io.ReadAll(hrsp.Body)
-			if rerr != nil {
-				return errors.InternalServerError("go.micro.client", rerr.Error())
-			}
-			return errors.New("go.micro.client", string(buf), int32(hrsp.StatusCode))
+		buf, berr := io.ReadAll(hrsp.Body)
+		if berr != nil {
+			return body.error(berr)
 		}
 
-		if cerr := cf.ReadBody(hrsp.Body, rerr); cerr != nil {
-			return errors.InternalServerError("go.micro.client", cerr.Error())
-		}
-
-		if err, ok = rerr.(error); !ok {
-			err = &Error{rerr}
+		if !ok || rerr == nil {
+			return responseError(req.Service(), hrsp.StatusCode, buf)
 		}
 
+		err = mappedError(req.Service(), hrsp.StatusCode, buf, cf, rerr)
 	}
 
 	return err
 }
 
+// mappedError unmarshals error body into error from error map,
+// body not matched mapped error type returned as is
+func mappedError(service string, status int, buf []byte, cf codec.Codec, rerr interface{}) error {
+	if cerr := cf.Unmarshal(buf, rerr); cerr != nil {
+		return responseError(service, status, buf)
+	}
+	if err, ok := rerr.(error); ok {
+		return err
+	}
+	return &Error{rerr}
+}
+
 type tag struct {
 	key  string
 	name string
 }
 
+// joinPath appends endpoint to base path with single slash between them
+func joinPath(base, endpoint string) string {
+	switch {
+	case base == "" || base == "/":
+		if endpoint == "" || endpoint[0] != '/' {
+			endpoint = "/" + endpoint
+		}
+		return endpoint
+	case endpoint == "" || endpoint[0] == '?':
+		return base + endpoint
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(endpoint, "/")
+}
+
+// noBodyMethod reports whether request of method sent without body
+func noBodyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// fieldTag returns name of field from first found tag
+func fieldTag(fld reflect.StructField, tags []string) *tag {
+	t := &tag{}
+	for _, tn := range tags {
+		ts, ok := fld.Tag.Lookup(tn)
+		if !ok {
+			continue
+		}
+
+		tp := strings.Split(ts, ",")
+		// special
+		switch tn {
+		case "protobuf": // special
+			for _, p := range tp {
+				if idx := strings.Index(p, "name="); idx > 0 {
+					t = &tag{key: tn, name: p[idx:]}
+				}
+			}
+		default:
+			t = &tag{key: tn, name: tp[0]}
+		}
+		if t.name != "" {
+			break
+		}
+	}
+	return t
+}
+
+// addQueryValues adds field value to query, nested messages flattened with dot separated
+// field names (filter.name=x), map entries added as name[key]=value and repeated fields
+// added as repeated parameters
+func addQueryValues(values url.Values, name string, val reflect.Value, tags []string) {
+	if !val.IsValid() {
+		return
+	}
+
+	if tm, ok := val.Interface().(encoding.TextMarshaler); ok {
+		if val.Kind() == reflect.Ptr && val.IsNil() {
+			return
+		}
+		if buf, err := tm.MarshalText(); err == nil {
+			values.Add(name, string(buf))
+			return
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return
+		}
+		if val.Kind() == reflect.Ptr && strings.HasPrefix(reflect.Indirect(val).Type().String(), "wrapperspb.") {
+			values.Add(name, getParam(val))
+			return
+		}
+		addQueryValues(values, name, val.Elem(), tags)
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			fld := val.Type().Field(i)
+			if fld.PkgPath != "" || val.Field(i).IsZero() {
+				continue
+			}
+			fname := fieldTag(fld, tags).name
+			if fname == "" {
+				fname = strings.ToLower(fld.Name)
+			}
+			addQueryValues(values, name+"."+fname, val.Field(i), tags)
+		}
+	case reflect.Map:
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprintf("%v", keys[i].Interface()) < fmt.Sprintf("%v", keys[j].Interface())
+		})
+		for _, k := range keys {
+			addQueryValues(values, fmt.Sprintf("%s[%v]", name, k.Interface()), val.MapIndex(k), tags)
+		}
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < val.Len(); idx++ {
+			addQueryValues(values, name, val.Index(idx), tags)
+		}
+	default:
+		values.Add(name, getParam(val))
+	}
+}
+
 func getParam(val reflect.Value) string {
 	var v string
 	switch val.Kind() {
@@ -341,3 +676,246 @@ func getParam(val reflect.Value) string {
 	}
 	return v
 }
+
+// keepAliveDialer configures tcp keep-alive of connections returned by custom dialer
+func keepAliveDialer(dial func(context.Context, string) (net.Conn, error), d time.Duration) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		tc, ok := conn.(*net.TCPConn)
+		if !ok {
+			return conn, nil
+		}
+		if d < 0 {
+			err = tc.SetKeepAlive(false)
+		} else if err = tc.SetKeepAlive(true); err == nil && d > 0 {
+			err = tc.SetKeepAlivePeriod(d)
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// limitedBody aborts streamed request body if it exceeds limit
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	n     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if b.n > b.limit {
+		return n, errors.BadRequest("go.micro.client", fmt.Sprintf("request body size exceeds limit %d", b.limit))
+	}
+	return n, err
+}
+
+// replayable reports whether request body can be sent again by retry, hedged call
+// or repeated request, streamed body is read only once
+func replayable(req client.Request) bool {
+	rctx := requestContext(req)
+	return rctx.Value(bodyReaderKey{}) == nil && rctx.Value(multipartFormKey{}) == nil && rctx.Value(ndjsonKey{}) == nil
+}
+
+// closeBody closes body of request that not sent, so streamed body writers are released
+func closeBody(hreq *http.Request) {
+	if hreq.Body != nil {
+		_ = hreq.Body.Close()
+	}
+}
+
+// formatTimeout returns Timeout header value in given format
+func formatTimeout(d time.Duration, f TimeoutFormat) string {
+	switch f {
+	case TimeoutSeconds:
+		return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+	case TimeoutDuration:
+		return d.String()
+	}
+	return strconv.FormatInt(int64(d), 10)
+}
+
+// copyOutgoingMetadata copies outgoing metadata, so changes made by wrappers not leak across attempts
+func copyOutgoingMetadata(ctx context.Context) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		return metadata.NewOutgoingContext(ctx, metadata.Copy(md))
+	}
+	return ctx
+}
+
+// newResponse returns new zero value of response type, or nil if response is not a pointer
+func newResponse(rsp interface{}) interface{} {
+	v := reflect.ValueOf(rsp)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	return reflect.New(v.Elem().Type()).Interface()
+}
+
+// allocResponse allocates nil intermediate pointers of response, so it can be
+// passed as *T, **T or pointer to nil *T, returns innermost pointer
+func allocResponse(rsp interface{}) (interface{}, error) {
+	v := reflect.ValueOf(rsp)
+	if v.Kind() != reflect.Ptr {
+		return rsp, nil
+	}
+	if v.IsNil() {
+		return nil, fmt.Errorf("nil response pointer %T", rsp)
+	}
+	for v.Elem().Kind() == reflect.Ptr {
+		if v.Elem().IsNil() {
+			v.Elem().Set(reflect.New(v.Elem().Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), nil
+}
+
+// jsonContentType returns ct if it is json content type, otherwise application/json
+func jsonContentType(ct string) string {
+	if isJSON(ct) {
+		return ct
+	}
+	return "application/json"
+}
+
+// isJSON reports whether ct is json content type
+func isJSON(ct string) bool {
+	return strings.Contains(ct, "json")
+}
+
+// formatDeadline returns deadline header value in given format
+func formatDeadline(d time.Time, f DeadlineFormat) string {
+	if f == DeadlineUnixMillis {
+		return strconv.FormatInt(d.UnixNano()/int64(time.Millisecond), 10)
+	}
+	return d.UTC().Format(time.RFC3339Nano)
+}
+
+// decodeError converts error response via ErrorDecoder if it provided
+func decodeError(service string, hrsp *http.Response, opts client.CallOptions) (bool, error) {
+	fn, ok := opts.Context.Value(errorDecoderKey{}).(ErrorDecoder)
+	if !ok || fn == nil || hrsp.StatusCode < 400 {
+		return false, nil
+	}
+	buf, err := io.ReadAll(hrsp.Body)
+	if err != nil {
+		return true, errors.InternalServerError("go.micro.client", err.Error())
+	}
+	if err = fn(hrsp.StatusCode, hrsp.Header, buf); err == nil {
+		// decoder must not turn error response to success
+		err = responseError(service, hrsp.StatusCode, buf)
+	}
+	return true, err
+}
+
+// streamResponse copies body to w, read errors mapped to client errors
+func streamResponse(ctx context.Context, w io.Writer, body io.Reader) error {
+	if _, err := io.Copy(w, body); err != nil {
+		if ctx.Err() != nil {
+			return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
+		}
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			return errors.Timeout("go.micro.client", err.Error())
+		}
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+	return nil
+}
+
+// setHeader sets header from metadata, raw key written without canonicalization,
+// value with MetadataValueSeparator sent as repeated header
+func setHeader(header http.Header, k, v string, raw bool) {
+	values := strings.Split(v, MetadataValueSeparator)
+	if !raw {
+		header.Del(k)
+		for _, hv := range values {
+			header.Add(k, hv)
+		}
+		return
+	}
+	// remove canonical duplicate
+	if ck := http.CanonicalHeaderKey(k); ck != k {
+		header.Del(ck)
+	}
+	header[k] = values
+}
+
+// trackedBody remembers error of response body read,
+// so truncated body can be distinguished from malformed one
+type trackedBody struct {
+	io.ReadCloser
+	err error
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// error returns 502 error if body not fully read because of connection error
+// or unexpected EOF, so call can be retried, 408 error if read interrupted by
+// context, other errors means malformed body
+func (b *trackedBody) error(err error) error {
+	if b != nil && b.err != nil {
+		var nerr net.Error
+		switch {
+		case stderrors.Is(b.err, context.Canceled), stderrors.Is(b.err, context.DeadlineExceeded):
+			return errors.New("go.micro.client", fmt.Sprintf("incomplete response body: %v", b.err), http.StatusRequestTimeout)
+		case stderrors.Is(b.err, io.ErrUnexpectedEOF), stderrors.As(b.err, &nerr):
+			return errors.New("go.micro.client", fmt.Sprintf("incomplete response body: %v", b.err), http.StatusBadGateway)
+		}
+	}
+	return errors.InternalServerError("go.micro.client", err.Error())
+}
+
+// metadataFilter decides which metadata keys sent as headers
+type metadataFilter struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+func newMetadataFilter(allow []string, deny []string) metadataFilter {
+	keys := func(list []string) map[string]struct{} {
+		if len(list) == 0 {
+			return nil
+		}
+		m := make(map[string]struct{}, len(list))
+		for _, k := range list {
+			m[http.CanonicalHeaderKey(k)] = struct{}{}
+		}
+		return m
+	}
+	return metadataFilter{allow: keys(allow), deny: keys(deny)}
+}
+
+func (f metadataFilter) allowed(k string) bool {
+	k = http.CanonicalHeaderKey(k)
+	if _, ok := f.deny[k]; ok {
+		return false
+	}
+	if f.allow == nil {
+		return true
+	}
+	_, ok := f.allow[k]
+	return ok
+}
+
+// responseError returns error for response with error status, error id is service name,
+// code is response status and detail is raw response body
+func responseError(service string, status int, body []byte) error {
+	if service == "" {
+		service = "go.micro.client"
+	}
+	return errors.New(service, string(body), int32(status))
+}
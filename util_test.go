@@ -1,8 +1,15 @@
 package http
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
+
+	"go.unistack.org/micro/v3/client"
+	"go.unistack.org/micro/v3/codec"
 )
 
 func TestParsing(t *testing.T) {
@@ -59,6 +66,41 @@ func TestNewPathRequest(t *testing.T) {
 	}
 }
 
+func TestNewPathQueryRequest(t *testing.T) {
+	type Filter struct {
+		Name string `json:"name"`
+		Tags []string
+	}
+	type Message struct {
+		Filter  *Filter           `json:"filter"`
+		Labels  map[string]string `json:"labels"`
+		Page    Filter            `json:"page"`
+		Created time.Time         `json:"created"`
+		Limit   int64             `json:"limit"`
+	}
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	omsg := &Message{
+		Filter:  &Filter{Name: "a", Tags: []string{"x", "y"}},
+		Labels:  map[string]string{"b": "2", "a": "1"},
+		Created: created,
+		Limit:   10,
+	}
+
+	for _, m := range []string{"GET", "HEAD"} {
+		path, nmsg, err := newPathRequest("/v1/test", m, "*", omsg, []string{"json"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nmsg != nil {
+			t.Fatalf("%s request must not have body: %#+v", m, nmsg)
+		}
+		if exp := "/v1/test?created=2020-01-02T03%3A04%3A05Z&filter.name=a&filter.tags=x&filter.tags=y&labels%5Ba%5D=1&labels%5Bb%5D=2&limit=10"; path != exp {
+			t.Fatalf("invalid path %s", path)
+		}
+	}
+}
+
 func TestNewPathVarRequest(t *testing.T) {
 	type Message struct {
 		Name string `json:"name"`
@@ -97,3 +139,108 @@ func TestNewPathVarRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestNewPathBodyRequest(t *testing.T) {
+	type Data struct {
+		Value string `json:"value"`
+	}
+	type Message struct {
+		Name string `json:"name"`
+		Data *Data  `json:"data"`
+	}
+
+	omsg := &Message{Name: "test_name", Data: &Data{Value: "test_value"}}
+
+	path, nmsg, err := newPathRequest("/v1/test", "POST", "*", omsg, []string{"json"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/test" || nmsg.(*Message).Name != "test_name" || nmsg.(*Message).Data.Value != "test_value" {
+		t.Fatalf("invalid path: %v nmsg: %#+v", path, nmsg)
+	}
+
+	path, nmsg, err = newPathRequest("/v1/test", "POST", "data", omsg, []string{"json"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/test?name=test_name" || nmsg.(*Message).Name != "" || nmsg.(*Message).Data.Value != "test_value" {
+		t.Fatalf("invalid path: %v nmsg: %#+v", path, nmsg)
+	}
+
+	if _, _, err = newPathRequest("/v1/test", "POST", "missing", omsg, []string{"json"}, nil); err == nil {
+		t.Fatal("expected error for missing body field")
+	}
+
+	if _, _, err = newPathRequest("/v1/test", "POST", "data", &Message{Name: "test_name"}, []string{"json"}, nil); err == nil {
+		t.Fatal("expected error for nil body field")
+	}
+}
+
+func TestArrayRootField(t *testing.T) {
+	type Item struct {
+		Name string `json:"name"`
+	}
+	type Response struct {
+		Items []*Item
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(` [{"name":"first"},{"name":"second"}]`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(client.Codec("application/json", codec.NewCodec()))
+
+	rsp := &Response{}
+	req := c.NewRequest("test", "/test", &struct{}{})
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress(ts.URL), Method(http.MethodGet), WithArrayRootField("Items")); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Items) != 2 || rsp.Items[1].Name != "second" {
+		t.Fatalf("invalid response %#+v", rsp)
+	}
+}
+
+func TestPathResolution(t *testing.T) {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		res      PathResolution
+		addr     string
+		endpoint string
+		expect   string
+	}{
+		{PathJoin, ts.URL, "/v1/users", "/v1/users"},
+		{PathJoin, ts.URL + "/api", "/v1/users", "/api/v1/users"},
+		{PathJoin, ts.URL + "/api/", "v1/users", "/api/v1/users"},
+		{PathJoin, ts.URL, "v1/users", "/v1/users"},
+		{PathReference, ts.URL + "/api/v0", "/v1/users", "/v1/users"},
+		{PathReference, ts.URL + "/api/v0", "v1/users", "/api/v1/users"},
+		{PathReference, ts.URL + "/api/", "v1/users", "/api/v1/users"},
+	}
+
+	for _, tt := range tests {
+		c := NewClient(client.Codec("application/json", codec.NewCodec()), WithPathResolution(tt.res))
+		if err := c.Call(context.TODO(), c.NewRequest("test", tt.endpoint, &Request{}), &Request{}, client.WithAddress(tt.addr)); err != nil {
+			t.Fatal(err)
+		}
+		if path != tt.expect {
+			t.Fatalf("%s + %s: expected %s, got %s", tt.addr, tt.endpoint, tt.expect, path)
+		}
+
+		// path option used instead of endpoint
+		if err := c.Call(context.TODO(), c.NewRequest("test", "/ignored", &Request{}), &Request{}, client.WithAddress(tt.addr), Path(tt.endpoint)); err != nil {
+			t.Fatal(err)
+		}
+		if path != tt.expect {
+			t.Fatalf("%s + path %s: expected %s, got %s", tt.addr, tt.endpoint, tt.expect, path)
+		}
+	}
+}